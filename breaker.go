@@ -0,0 +1,347 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen matches the *BreakerOpenError returned by CircuitBreaker by errors.Is.
+var ErrBreakerOpen = errors.New("goproc: circuit breaker is open")
+
+// Task defines the function type of error-returning tasks.
+type Task func(ctx context.Context) error
+
+// BreakerState is the state of CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed permits all tasks, and trips to BreakerOpen by failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all tasks until cool-down, and then switches to BreakerHalfOpen.
+	BreakerOpen
+	// BreakerHalfOpen permits limited trial tasks, which close the breaker if all succeed, or open
+	// it again on any failure.
+	BreakerHalfOpen
+)
+
+// String implements fmt.Stringer.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerOpenError is returned for tasks rejected by CircuitBreaker.
+type BreakerOpenError struct {
+	State   BreakerState // BreakerOpen, or BreakerHalfOpen if trials are exhausted
+	RetryAt time.Time    // end of cool-down, zero for BreakerHalfOpen
+}
+
+// Error implements error.
+func (e *BreakerOpenError) Error() string {
+	if e.State == BreakerOpen {
+		return fmt.Sprintf("goproc: circuit breaker is open until %s", e.RetryAt.Format(time.RFC3339Nano))
+	}
+	return fmt.Sprintf("goproc: circuit breaker is %v", e.State)
+}
+
+// Is makes errors.Is(err, ErrBreakerOpen) true.
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrBreakerOpen
+}
+
+// BreakerPolicy configures CircuitBreaker. At least one of ConsecutiveFailures and FailureRatio must
+// be set.
+type BreakerPolicy struct {
+	// ConsecutiveFailures trips the breaker after this many consecutive failures, 0 disables it.
+	ConsecutiveFailures int
+	// FailureRatio trips the breaker on a failure if the ratio of failures reaches it with at least
+	// MinRequests done, 0 disables it.
+	FailureRatio float64
+	MinRequests  int
+	// Interval clears counts periodically in BreakerClosed state, 0 means counts are only cleared
+	// on state changes.
+	Interval time.Duration
+	// CoolDown is the duration of BreakerOpen state before switching to BreakerHalfOpen, it must
+	// be positive.
+	CoolDown time.Duration
+	// HalfOpenMax is the number of trial tasks in BreakerHalfOpen state, 0 means 1.
+	HalfOpenMax int
+	// IsFailure classifies errors, nil means all errors are failures.
+	IsFailure func(err error) bool
+	// OnStateChange is called after each state change, it's optional.
+	OnStateChange func(from, to BreakerState)
+}
+
+// BreakerCounts contains the counts of tasks done in the current state or interval, returned from
+// CircuitBreaker.Counts().
+type BreakerCounts struct {
+	Requests            int
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	Rejected            int64 // tasks ever rejected
+}
+
+// String implements fmt.Stringer.
+func (c BreakerCounts) String() string {
+	return fmt.Sprintf("BreakerCounts: Requests=%d Successes=%d Failures=%d ConsecutiveFailures=%d Rejected=%d",
+		c.Requests, c.Successes, c.Failures, c.ConsecutiveFailures, c.Rejected)
+}
+
+// breakerCoolDown is the Deadliner scheduled for the end of a cool-down.
+type breakerCoolDown struct {
+	generation uint64
+	at         time.Time
+}
+
+func (c breakerCoolDown) Deadline() time.Time {
+	return c.at
+}
+
+type breakerChange struct {
+	from, to BreakerState
+}
+
+// CircuitBreaker short-circuits tasks with *BreakerOpenError while a dependency is failing. Cool-
+// downs are scheduled by a TimeoutChan and handled by a goroutine owned by the breaker Controller,
+// so that state changes are observed on time even without tasks.
+type CircuitBreaker struct {
+	policy BreakerPolicy
+	ctrl   *Controller
+	tc     *TimeoutChan
+
+	mu         *sync.Mutex
+	state      BreakerState
+	generation uint64 // increases on each state change and interval, tasks of old generations are ignored
+	counts     BreakerCounts
+	expiry     time.Time // end of the current interval or cool-down
+	trials     int       // trial tasks started in BreakerHalfOpen state
+	changes    []breakerChange
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker in BreakerClosed state, cool-downs are handled
+// within resolution.
+func NewCircuitBreaker(ctx context.Context, resolution time.Duration, policy BreakerPolicy) *CircuitBreaker {
+	if policy.ConsecutiveFailures <= 0 && policy.FailureRatio <= 0 {
+		panic("goproc: no trip condition")
+	}
+	if policy.CoolDown <= 0 {
+		panic("goproc: non-positive cool-down")
+	}
+	if policy.HalfOpenMax <= 0 {
+		policy.HalfOpenMax = 1
+	}
+	b := &CircuitBreaker{
+		policy: policy,
+		ctrl:   NewController(ctx, "CircuitBreaker"),
+		tc:     NewTimeoutChan(ctx, resolution, 0),
+		mu:     &sync.Mutex{},
+	}
+	b.mu.Lock()
+	b.setStateLocked(BreakerClosed, time.Now()) // starts the first interval
+	b.mu.Unlock()
+	b.ctrl.Go(b.coolDownProcess)
+	return b
+}
+
+// State returns the current state of b.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+	b.refreshLocked(time.Now())
+	return b.state
+}
+
+// Counts returns the counts of b.
+func (b *CircuitBreaker) Counts() BreakerCounts {
+	b.mu.Lock()
+	defer b.unlock()
+	b.refreshLocked(time.Now())
+	return b.counts
+}
+
+// Execute runs task if b permits, and records its result. It returns a *BreakerOpenError without
+// running task if b rejects it, or the error returned by task otherwise. A panic of task is
+// recorded as a failure.
+func (b *CircuitBreaker) Execute(ctx context.Context, task Task) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	done := false
+	defer func() {
+		if !done {
+			b.record(generation, false) // task panics
+		}
+	}()
+	err = task(ctx)
+	done = true
+	b.record(generation, err == nil || (b.policy.IsFailure != nil && !b.policy.IsFailure(err)))
+	return err
+}
+
+// Go runs task on a goroutine owned by c through b.Execute, and returns a channel which receives
+// the result. The task is short-circuited without starting a goroutine if b is open. Note that the
+// channel never receives if c is cancelled before the goroutine runs, e.g. when waiting for the rate
+// limiter of c.
+func (b *CircuitBreaker) Go(c *Controller, task Task) <-chan error {
+	result := make(chan error, 1)
+	if err := b.check(); err != nil {
+		result <- err
+		return result
+	}
+	c.Go(func(ctx context.Context) {
+		result <- b.Execute(ctx, task)
+	})
+	return result
+}
+
+// Close stops handling cool-downs. A closed breaker still works, with state changes observed lazily
+// on calls.
+func (b *CircuitBreaker) Close() {
+	b.ctrl.Shutdown()
+	b.tc.Shutdown()
+}
+
+// unlock unlocks b.mu, and then calls OnStateChange for state changes made with b.mu held.
+func (b *CircuitBreaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	if b.policy.OnStateChange != nil {
+		for _, c := range changes {
+			b.policy.OnStateChange(c.from, c.to)
+		}
+	}
+}
+
+// check returns a *BreakerOpenError if b is open.
+func (b *CircuitBreaker) check() error {
+	b.mu.Lock()
+	defer b.unlock()
+	b.refreshLocked(time.Now())
+	if b.state == BreakerOpen {
+		b.counts.Rejected++
+		return &BreakerOpenError{State: BreakerOpen, RetryAt: b.expiry}
+	}
+	return nil
+}
+
+// allow returns the current generation if b permits a task, or a *BreakerOpenError otherwise.
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+	b.refreshLocked(time.Now())
+	switch b.state {
+	case BreakerOpen:
+		b.counts.Rejected++
+		return 0, &BreakerOpenError{State: BreakerOpen, RetryAt: b.expiry}
+	case BreakerHalfOpen:
+		if b.trials >= b.policy.HalfOpenMax {
+			b.counts.Rejected++
+			return 0, &BreakerOpenError{State: BreakerHalfOpen}
+		}
+		b.trials++
+	}
+	return b.generation, nil
+}
+
+// record records the result of a task permitted in generation.
+func (b *CircuitBreaker) record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	b.refreshLocked(now)
+	if generation != b.generation {
+		return // stale
+	}
+	b.counts.Requests++
+	if success {
+		b.counts.Successes++
+		b.counts.ConsecutiveFailures = 0
+		if b.state == BreakerHalfOpen && b.counts.Successes >= b.policy.HalfOpenMax {
+			b.setStateLocked(BreakerClosed, now)
+		}
+		return
+	}
+	b.counts.Failures++
+	b.counts.ConsecutiveFailures++
+	if b.state == BreakerHalfOpen || b.tripLocked() {
+		b.setStateLocked(BreakerOpen, now)
+	}
+}
+
+// tripLocked returns whether the counts trip b in BreakerClosed state. It should be called with
+// b.mu held.
+func (b *CircuitBreaker) tripLocked() bool {
+	p := b.policy
+	if p.ConsecutiveFailures > 0 && b.counts.ConsecutiveFailures >= p.ConsecutiveFailures {
+		return true
+	}
+	return p.FailureRatio > 0 && b.counts.Requests >= p.MinRequests &&
+		float64(b.counts.Failures) >= p.FailureRatio*float64(b.counts.Requests)
+}
+
+// refreshLocked ends the current interval or cool-down if it expires at now. It should be called
+// with b.mu held.
+func (b *CircuitBreaker) refreshLocked(now time.Time) {
+	if b.expiry.IsZero() || now.Before(b.expiry) {
+		return
+	}
+	switch b.state {
+	case BreakerClosed:
+		b.setStateLocked(BreakerClosed, now) // new interval
+	case BreakerOpen:
+		b.setStateLocked(BreakerHalfOpen, now)
+	}
+}
+
+// setStateLocked switches b to state at now, and starts a new generation. It should be called with
+// b.mu held.
+func (b *CircuitBreaker) setStateLocked(state BreakerState, now time.Time) {
+	if state != b.state {
+		b.changes = append(b.changes, breakerChange{from: b.state, to: state})
+	}
+	b.state = state
+	b.generation++
+	b.counts = BreakerCounts{Rejected: b.counts.Rejected}
+	b.trials = 0
+	b.expiry = time.Time{}
+	switch state {
+	case BreakerClosed:
+		if b.policy.Interval > 0 {
+			b.expiry = now.Add(b.policy.Interval)
+		}
+	case BreakerOpen:
+		b.expiry = now.Add(b.policy.CoolDown)
+		// error is ignored as b is closed
+		b.tc.Push(breakerCoolDown{generation: b.generation, at: b.expiry})
+	}
+}
+
+func (b *CircuitBreaker) coolDownProcess(ctx context.Context) {
+	for {
+		select {
+		case d := <-b.tc.Out:
+			c := d.(breakerCoolDown)
+			b.mu.Lock()
+			if c.generation == b.generation && b.state == BreakerOpen {
+				b.setStateLocked(BreakerHalfOpen, time.Now())
+			}
+			b.unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("With circuit breaker setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testCoolDown   = 50 * time.Millisecond
+		)
+		var (
+			errTest = errors.New("test")
+			mu      sync.Mutex
+			changes []string
+			policy  = BreakerPolicy{
+				ConsecutiveFailures: 3,
+				CoolDown:            testCoolDown,
+				OnStateChange: func(from, to BreakerState) {
+					mu.Lock()
+					defer mu.Unlock()
+					changes = append(changes, fmt.Sprintf("%v->%v", from, to))
+				},
+			}
+			fail    = func(ctx context.Context) error { return errTest }
+			succeed = func(ctx context.Context) error { return nil }
+			history = func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string{}, changes...)
+			}
+		)
+
+		Convey("Test consecutive failures", func() {
+			b := NewCircuitBreaker(context.Background(), testResolution, policy)
+			defer b.Close()
+			So(b.Execute(context.Background(), fail), ShouldEqual, errTest)
+			So(b.Execute(context.Background(), fail), ShouldEqual, errTest)
+			So(b.Execute(context.Background(), succeed), ShouldBeNil) // resets consecutive failures
+			for i := 0; i < 3; i++ {
+				So(b.Execute(context.Background(), fail), ShouldEqual, errTest)
+			}
+			So(b.State(), ShouldEqual, BreakerOpen)
+
+			err := b.Execute(context.Background(), succeed)
+			fmt.Println(err)
+			So(errors.Is(err, ErrBreakerOpen), ShouldBeTrue)
+			var oerr *BreakerOpenError
+			So(errors.As(err, &oerr), ShouldBeTrue)
+			So(oerr.RetryAt, ShouldHappenAfter, time.Now())
+
+			time.Sleep(testCoolDown + 4*testResolution)
+			So(history(), ShouldResemble, []string{"closed->open", "open->half-open"})
+			So(b.State(), ShouldEqual, BreakerHalfOpen)
+			So(b.Execute(context.Background(), succeed), ShouldBeNil)
+			So(b.State(), ShouldEqual, BreakerClosed)
+			So(history(), ShouldResemble, []string{"closed->open", "open->half-open", "half-open->closed"})
+			fmt.Println(b.Counts())
+			So(b.Counts().Rejected, ShouldEqual, 1)
+		})
+		Convey("Test failure ratio", func() {
+			policy.ConsecutiveFailures = 0
+			policy.FailureRatio = 0.5
+			policy.MinRequests = 4
+			b := NewCircuitBreaker(context.Background(), testResolution, policy)
+			defer b.Close()
+			for _, task := range []Task{succeed, fail, fail} {
+				b.Execute(context.Background(), task)
+			}
+			So(b.State(), ShouldEqual, BreakerClosed) // less than min requests
+			b.Execute(context.Background(), succeed)
+			So(b.State(), ShouldEqual, BreakerClosed) // trips on failures only
+			b.Execute(context.Background(), fail)
+			So(b.State(), ShouldEqual, BreakerOpen)
+		})
+		Convey("Test half-open trials", func() {
+			policy.HalfOpenMax = 2
+			policy.IsFailure = func(err error) bool { return err != context.Canceled }
+			b := NewCircuitBreaker(context.Background(), testResolution, policy)
+			defer b.Close()
+			for i := 0; i < 3; i++ {
+				b.Execute(context.Background(), func(ctx context.Context) error { return context.Canceled })
+			}
+			So(b.State(), ShouldEqual, BreakerClosed) // not failures
+			for i := 0; i < 3; i++ {
+				b.Execute(context.Background(), fail)
+			}
+			time.Sleep(testCoolDown + 4*testResolution)
+
+			var (
+				release = make(chan struct{})
+				ctrl    = NewController(context.Background(), t.Name())
+				blocked = func(ctx context.Context) error { <-release; return nil }
+				r1, r2  = b.Go(ctrl, blocked), b.Go(ctrl, blocked)
+			)
+			time.Sleep(4 * testResolution)
+			err := b.Execute(context.Background(), succeed) // trials exhausted
+			So(err.(*BreakerOpenError).State, ShouldEqual, BreakerHalfOpen)
+			close(release)
+			So(<-r1, ShouldBeNil)
+			So(<-r2, ShouldBeNil)
+			So(b.State(), ShouldEqual, BreakerClosed)
+
+			for i := 0; i < 3; i++ {
+				b.Execute(context.Background(), fail)
+			}
+			So(errors.Is(<-b.Go(ctrl, succeed), ErrBreakerOpen), ShouldBeTrue) // short-circuited
+			time.Sleep(testCoolDown + 4*testResolution)
+			So(<-b.Go(ctrl, fail), ShouldEqual, errTest)
+			So(b.State(), ShouldEqual, BreakerOpen) // trial failure
+			ctrl.Wait()
+		})
+		Convey("Test panic and interval", func() {
+			policy.Interval = testCoolDown
+			b := NewCircuitBreaker(context.Background(), testResolution, policy)
+			b.Close() // changes are observed lazily
+			So(func() {
+				b.Execute(context.Background(), func(ctx context.Context) error { panic("oops") })
+			}, ShouldPanicWith, "oops")
+			So(b.Counts().ConsecutiveFailures, ShouldEqual, 1)
+			time.Sleep(testCoolDown)
+			So(b.Counts().ConsecutiveFailures, ShouldEqual, 0) // new interval
+			for i := 0; i < 3; i++ {
+				b.Execute(context.Background(), fail)
+			}
+			time.Sleep(testCoolDown)
+			So(b.State(), ShouldEqual, BreakerHalfOpen)
+		})
+	})
+}
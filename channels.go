@@ -0,0 +1,242 @@
+package goproc
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Router defines the function type choosing the output index in [0, n) of a Deadliner for FanOut.
+type Router func(d Deadliner, n int) int
+
+// RoundRobin returns a Router which chooses outputs in turn.
+func RoundRobin() Router {
+	var next uint64
+	return func(d Deadliner, n int) int {
+		return int((atomic.AddUint64(&next, 1) - 1) % uint64(n))
+	}
+}
+
+// HashKey returns a Router which chooses outputs by hash of Keyed.Key, so that Deadliners of the
+// same key always go to the same output. Deadliners not implementing Keyed go to the first output.
+func HashKey() Router {
+	return func(d Deadliner, n int) int {
+		k, ok := d.(Keyed)
+		if !ok {
+			return 0
+		}
+		h := fnv.New32a()
+		h.Write([]byte(k.Key()))
+		return int(h.Sum32() % uint32(n))
+	}
+}
+
+// Merge merges ins into the returned channel on goroutines owned by c. The returned channel is
+// closed when all ins are closed, or c is cancelled.
+func Merge(c *Controller, ins ...<-chan Deadliner) <-chan Deadliner {
+	var (
+		out = make(chan Deadliner)
+		wg  = &sync.WaitGroup{}
+	)
+	wg.Add(len(ins))
+	for _, in := range ins {
+		in := in
+		c.Go(func(ctx context.Context) {
+			defer wg.Done()
+			for {
+				d, ok := receiveDeadliner(ctx, in)
+				if !ok || !sendDeadliner(ctx, out, d) {
+					return
+				}
+			}
+		})
+	}
+	c.Go(func(ctx context.Context) {
+		wg.Wait()
+		close(out)
+	})
+	return out
+}
+
+// FanOut distributes Deadliners from in to n returned channels chosen by route on a goroutine owned
+// by c. A slow output blocks the others. The returned channels are closed when in is closed, or c
+// is cancelled.
+func FanOut(c *Controller, in <-chan Deadliner, n int, route Router) []<-chan Deadliner {
+	outs, chans := makeDeadlinerChans(n)
+	c.Go(func(ctx context.Context) {
+		defer closeDeadlinerChans(outs)
+		for {
+			d, ok := receiveDeadliner(ctx, in)
+			if !ok || !sendDeadliner(ctx, outs[route(d, n)], d) {
+				return
+			}
+		}
+	})
+	return chans
+}
+
+// Tee copies each Deadliner from in to all of n returned channels on a goroutine owned by c, thus
+// the slowest output paces the others. The returned channels are closed when in is closed, or c is
+// cancelled.
+func Tee(c *Controller, in <-chan Deadliner, n int) []<-chan Deadliner {
+	outs, chans := makeDeadlinerChans(n)
+	c.Go(func(ctx context.Context) {
+		defer closeDeadlinerChans(outs)
+		for {
+			d, ok := receiveDeadliner(ctx, in)
+			if !ok {
+				return
+			}
+			for _, out := range outs {
+				if !sendDeadliner(ctx, out, d) {
+					return
+				}
+			}
+		}
+	})
+	return chans
+}
+
+// mergeHead is the head Deadliner of an OrderedMerge input.
+type mergeHead struct {
+	d      Deadliner
+	src    int
+	closed bool
+}
+
+func (h mergeHead) Priority() int64 {
+	return h.d.Deadline().UnixNano()
+}
+
+// OrderedMerge merges ins, each of which is in deadline order like TimeoutChan.Out, into the
+// returned channel in global deadline order on goroutines owned by c. It's a k-way merge holding
+// the head of each input, which sends the earliest head once every open input has a head.
+//
+// As inputs like TimeoutChan.Out may stay quiet for long, a positive window also sends the
+// earliest head once it's window later than its deadline - window should cover the delay of
+// inputs, e.g. the resolution of TimeoutChans. The returned channel is closed when all ins are
+// closed, or c is cancelled.
+func OrderedMerge(c *Controller, window time.Duration, ins ...<-chan Deadliner) <-chan Deadliner {
+	var (
+		out   = make(chan Deadliner)
+		heads = make(chan mergeHead)
+		wants = make([]chan struct{}, len(ins))
+	)
+	for i, in := range ins {
+		i, in := i, in
+		wants[i] = make(chan struct{}, 1)
+		wants[i] <- struct{}{}
+		c.Go(func(ctx context.Context) {
+			for {
+				select {
+				case <-wants[i]:
+				case <-ctx.Done():
+					return
+				}
+				d, ok := receiveDeadliner(ctx, in)
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case heads <- mergeHead{d: d, src: i, closed: !ok}:
+				case <-ctx.Done():
+					return
+				}
+				if !ok {
+					return
+				}
+			}
+		})
+	}
+	c.Go(func(ctx context.Context) {
+		defer close(out)
+		var (
+			pq      = NewPriorityQueue(false, len(ins))
+			open    = len(ins)
+			waiting = len(ins) // open inputs without heads in pq
+			timer   = time.NewTimer(window)
+			armed   bool // whether timer may fire without being received
+		)
+		if !timer.Stop() {
+			<-timer.C
+		}
+		defer timer.Stop()
+		for open > 0 || pq.Len() > 0 {
+			var (
+				send chan<- Deadliner
+				next mergeHead
+				wake <-chan time.Time
+			)
+			if pq.Len() > 0 {
+				next = pq.Peek().(mergeHead)
+				if waiting == 0 {
+					send = out
+				} else if window > 0 {
+					if wait := time.Until(next.d.Deadline().Add(window)); wait <= 0 {
+						send = out
+					} else {
+						if armed && !timer.Stop() {
+							<-timer.C
+						}
+						timer.Reset(wait)
+						armed, wake = true, timer.C
+					}
+				}
+			}
+			select {
+			case h := <-heads:
+				waiting--
+				if h.closed {
+					open--
+				} else {
+					pq.Insert(h)
+				}
+			case send <- next.d:
+				pq.Extract()
+				waiting++
+				wants[next.src] <- struct{}{}
+			case <-wake:
+				armed = false
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return out
+}
+
+func receiveDeadliner(ctx context.Context, in <-chan Deadliner) (Deadliner, bool) {
+	select {
+	case d, ok := <-in:
+		return d, ok
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func sendDeadliner(ctx context.Context, out chan<- Deadliner, d Deadliner) bool {
+	select {
+	case out <- d:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func makeDeadlinerChans(n int) ([]chan Deadliner, []<-chan Deadliner) {
+	outs := make([]chan Deadliner, n)
+	chans := make([]<-chan Deadliner, n)
+	for i := range outs {
+		outs[i] = make(chan Deadliner)
+		chans[i] = outs[i]
+	}
+	return outs, chans
+}
+
+func closeDeadlinerChans(outs []chan Deadliner) {
+	for _, out := range outs {
+		close(out)
+	}
+}
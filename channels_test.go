@@ -0,0 +1,161 @@
+package goproc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChannelHelpers(t *testing.T) {
+	Convey("With channel helpers setup", t, func(c C) {
+		const testItems = 30
+		var (
+			ctrl = NewController(context.Background(), t.Name())
+			base = time.Now()
+			at   = func(i int) time.Time { return base.Add(time.Duration(i) * time.Millisecond) }
+			feed = func(items ...Deadliner) <-chan Deadliner {
+				ch := make(chan Deadliner)
+				go func() {
+					defer close(ch)
+					for _, d := range items {
+						ch <- d
+					}
+				}()
+				return ch
+			}
+			stream = func(from, step, n int) <-chan Deadliner {
+				var items []Deadliner
+				for i := 0; i < n; i++ {
+					items = append(items, TestDeadliner{at(from + i*step)})
+				}
+				return feed(items...)
+			}
+			drain = func(ch <-chan Deadliner) (out []time.Time) {
+				for d := range ch {
+					out = append(out, d.Deadline())
+				}
+				return
+			}
+		)
+		defer ctrl.Shutdown()
+
+		Convey("Test merge", func() {
+			merged := drain(Merge(ctrl, stream(0, 3, 10), stream(1, 3, 10), stream(2, 3, 10)))
+			So(len(merged), ShouldEqual, testItems)
+			sort.Slice(merged, func(i, j int) bool { return merged[i].Before(merged[j]) })
+			for i, d := range merged {
+				So(d, ShouldEqual, at(i))
+			}
+		})
+		Convey("Test ordered merge", func() {
+			merged := drain(OrderedMerge(ctrl, 0, stream(0, 3, 10), stream(1, 3, 10), stream(2, 3, 10), feed()))
+			So(len(merged), ShouldEqual, testItems)
+			for i, d := range merged {
+				So(d, ShouldEqual, at(i))
+			}
+		})
+		Convey("Test ordered merge of timeout chans", func() {
+			const testResolution = 5 * time.Millisecond
+			var (
+				ctx   = context.Background()
+				tc1   = NewTimeoutChan(ctx, testResolution, 0)
+				tc2   = NewTimeoutChan(ctx, testResolution, 0)
+				start = time.Now().Add(50 * time.Millisecond)
+			)
+			defer tc1.Shutdown()
+			defer tc2.Shutdown()
+			out := OrderedMerge(ctrl, 2*testResolution, tc1.Out, tc2.Out)
+			for i := 0; i < 10; i++ {
+				tc := tc1
+				if i%3 == 0 {
+					tc = tc2 // tc2 stays quiet for most of the time
+				}
+				So(tc.Push(TestDeadliner{start.Add(time.Duration(i) * testResolution)}), ShouldBeNil)
+			}
+			for i := 0; i < 10; i++ {
+				So((<-out).Deadline(), ShouldEqual, start.Add(time.Duration(i)*testResolution))
+			}
+		})
+		Convey("Test fan out", func() {
+			var items []Deadliner
+			for i := 0; i < testItems; i++ {
+				items = append(items, testKeyedDeadliner{TestDeadliner{at(i)}, strconv.Itoa(i % 5)})
+			}
+			collect := func(route Router) (counts []int, owners map[string][]int) {
+				var (
+					outs   = FanOut(ctrl, feed(items...), 3, route)
+					result = make(chan []Deadliner, len(outs))
+				)
+				for _, out := range outs {
+					out := out
+					go func() {
+						var got []Deadliner
+						for d := range out {
+							got = append(got, d)
+						}
+						result <- got
+					}()
+				}
+				owners = make(map[string][]int)
+				for i := 0; i < len(outs); i++ {
+					got := <-result
+					counts = append(counts, len(got))
+					seen := make(map[string]bool)
+					for _, d := range got {
+						if key := d.(Keyed).Key(); !seen[key] {
+							seen[key] = true
+							owners[key] = append(owners[key], i)
+						}
+					}
+				}
+				return
+			}
+			counts, _ := collect(RoundRobin())
+			So(counts, ShouldResemble, []int{testItems / 3, testItems / 3, testItems / 3})
+			counts, owners := collect(HashKey())
+			So(counts[0]+counts[1]+counts[2], ShouldEqual, testItems)
+			So(len(owners), ShouldEqual, 5)
+			for _, outs := range owners {
+				So(len(outs), ShouldEqual, 1) // a key always goes to the same output
+			}
+
+			outs := FanOut(ctrl, stream(0, 1, 6), 3, RoundRobin())
+			for i := 0; i < 6; i++ {
+				So((<-outs[i%3]).Deadline(), ShouldEqual, at(i))
+			}
+		})
+		Convey("Test hash key routing", func() {
+			route := HashKey()
+			for i := 0; i < testItems; i++ {
+				d := testKeyedDeadliner{TestDeadliner{at(i)}, strconv.Itoa(i % 5)}
+				So(route(d, 3), ShouldEqual, route(testKeyedDeadliner{key: d.key}, 3))
+				So(route(d, 3), ShouldBeBetween, -1, 3)
+			}
+			So(route(TestDeadliner{}, 3), ShouldEqual, 0)
+		})
+		Convey("Test tee", func() {
+			outs := Tee(ctrl, stream(0, 1, 10), 2)
+			done := make(chan []time.Time)
+			go func() { done <- drain(outs[1]) }()
+			first := drain(outs[0])
+			So(first, ShouldResemble, <-done)
+			So(len(first), ShouldEqual, 10)
+		})
+		Convey("Test cancellation", func() {
+			merged := Merge(ctrl, make(chan Deadliner))
+			fanned := FanOut(ctrl, make(chan Deadliner), 2, RoundRobin())
+			ordered := OrderedMerge(ctrl, time.Second, make(chan Deadliner))
+			ctrl.Shutdown()
+			_, ok := <-merged
+			So(ok, ShouldBeFalse)
+			_, ok = <-fanned[1]
+			So(ok, ShouldBeFalse)
+			_, ok = <-ordered
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
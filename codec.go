@@ -0,0 +1,66 @@
+package goproc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec is the interface implemented by an object that can encode and decode items for
+// persistence.
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+type gobCodec struct {
+	newItem func() interface{}
+}
+
+// NewGobCodec creates a Codec with encoding/gob, newItem should return a pointer to a new zero
+// item for decoding into, which is also the decoded result.
+func NewGobCodec(newItem func() interface{}) Codec {
+	return gobCodec{newItem: newItem}
+}
+
+// Encode implements Codec.
+func (c gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Codec.
+func (c gobCodec) Decode(data []byte) (interface{}, error) {
+	v := c.newItem()
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type jsonCodec struct {
+	newItem func() interface{}
+}
+
+// NewJSONCodec creates a Codec with encoding/json, newItem should return a pointer to a new zero
+// item for decoding into, which is also the decoded result.
+func NewJSONCodec(newItem func() interface{}) Codec {
+	return jsonCodec{newItem: newItem}
+}
+
+// Encode implements Codec.
+func (c jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode implements Codec.
+func (c jsonCodec) Decode(data []byte) (interface{}, error) {
+	v := c.newItem()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -2,7 +2,9 @@ package goproc
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,13 +14,44 @@ type Goroutine func(ctx context.Context)
 // Recover defines the recover handler function type for Controller.
 type Recover func(r interface{})
 
+// ControllerStats contains controller statistics returned from Controller.Stats().
+type ControllerStats struct {
+	Started   int64 // goroutines ever started, excluding skipped ones
+	Running   int64 // goroutines currently running, including ones waiting for the rate limiter
+	Recovered int64 // panics recovered in goroutines started by GoWithRecover
+	Skipped   int64 // rate limited goroutines skipped as cancelled before being permitted
+}
+
+// String implements fmt.Stringer.
+func (s ControllerStats) String() string {
+	return fmt.Sprintf("ControllerStats: Started=%d Running=%d Recovered=%d Skipped=%d",
+		s.Started, s.Running, s.Recovered, s.Skipped)
+}
+
+// controllerStats holds the atomic counters of ControllerStats, shared by copies of a Controller.
+type controllerStats struct {
+	started   int64
+	running   int64
+	recovered int64
+	skipped   int64
+}
+
+// Limiter defines the rate limiter interface for Controller.WithRateLimit, which is implemented
+// by limiters in the ratelimit package.
+type Limiter interface {
+	// Wait blocks until an event is permitted, or returns an error if ctx is done before that.
+	Wait(ctx context.Context) error
+}
+
 // Controller implements a simple controller of goroutines, which can cancel
 // or wait for all under control goroutines to return.
 type Controller struct {
-	name   string
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     *sync.WaitGroup
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	stats   *controllerStats
+	limiter Limiter
 }
 
 // NewController creates a new goproc Controller.
@@ -29,19 +62,51 @@ func NewController(ctx context.Context, name string) *Controller {
 		ctx:    child,
 		cancel: cancel,
 		wg:     &sync.WaitGroup{},
+		stats:  &controllerStats{},
+	}
+}
+
+// Name returns the name of c.
+func (c *Controller) Name() string {
+	return c.name
+}
+
+// Stats returns Controller statistics, which are shared by copies of c returned from c.With* calls.
+func (c *Controller) Stats() ControllerStats {
+	return ControllerStats{
+		Started:   atomic.LoadInt64(&c.stats.started),
+		Running:   atomic.LoadInt64(&c.stats.running),
+		Recovered: atomic.LoadInt64(&c.stats.recovered),
+		Skipped:   atomic.LoadInt64(&c.stats.skipped),
 	}
 }
 
+// add adds a goroutine to c, which is counted as started by wait instead if c is rate limited.
+func (c *Controller) add() {
+	c.wg.Add(1)
+	if c.limiter == nil {
+		atomic.AddInt64(&c.stats.started, 1)
+	}
+	atomic.AddInt64(&c.stats.running, 1)
+}
+
+func (c *Controller) done() {
+	atomic.AddInt64(&c.stats.running, -1)
+	c.wg.Done()
+}
+
 // Go initiates a new goroutine for g and gains control on the goroutine through
 // a context.Context argument.
 func (c *Controller) Go(g Goroutine) *Controller {
 	if err := c.ctx.Err(); err != nil {
 		panic(err)
 	}
-	c.wg.Add(1)
+	c.add()
 	go func() {
-		defer c.wg.Done()
-		g(c.ctx)
+		defer c.done()
+		if c.wait() {
+			g(c.ctx)
+		}
 	}()
 	return c
 }
@@ -53,15 +118,18 @@ func (c *Controller) GoWithRecover(g Goroutine, rf Recover) *Controller {
 	if err := c.ctx.Err(); err != nil {
 		panic(err)
 	}
-	c.wg.Add(1)
+	c.add()
 	go func() {
-		defer c.wg.Done()
+		defer c.done()
 		defer func() {
 			if r := recover(); r != nil {
+				atomic.AddInt64(&c.stats.recovered, 1)
 				rf(r)
 			}
 		}()
-		g(c.ctx)
+		if c.wait() {
+			g(c.ctx)
+		}
 	}()
 	return c
 }
@@ -77,10 +145,12 @@ func (c *Controller) WithValue(key interface{}, value interface{}) *Controller {
 		panic(err)
 	}
 	return &Controller{
-		name:   c.name,
-		ctx:    context.WithValue(c.ctx, key, value),
-		cancel: c.cancel,
-		wg:     c.wg,
+		name:    c.name,
+		ctx:     context.WithValue(c.ctx, key, value),
+		cancel:  c.cancel,
+		wg:      c.wg,
+		stats:   c.stats,
+		limiter: c.limiter,
 	}
 }
 
@@ -101,7 +171,9 @@ func (c *Controller) WithDeadline(deadline time.Time) *Controller {
 			cancel()
 			c.cancel()
 		},
-		wg: c.wg,
+		wg:      c.wg,
+		stats:   c.stats,
+		limiter: c.limiter,
 	}
 }
 
@@ -122,8 +194,45 @@ func (c *Controller) WithTimeout(timeout time.Duration) *Controller {
 			cancel()
 			c.cancel()
 		},
-		wg: c.wg,
+		wg:      c.wg,
+		stats:   c.stats,
+		limiter: c.limiter,
+	}
+}
+
+// WithRateLimit returns a copy of c with limiter, which paces goroutines started in subsequent
+// c.Go* calls: c.Go* calls return immediately, while each started goroutine waits for limiter
+// before running its Goroutine function, and skips it if c is cancelled before being permitted.
+// Passing a nil limiter removes rate limiting from the copy.
+//
+// Note that unlike a child context, the returned object still holds the control of c, which means
+// cancelling the returned Controller would actually cancel all goroutines started by c.
+func (c *Controller) WithRateLimit(limiter Limiter) *Controller {
+	if err := c.ctx.Err(); err != nil {
+		panic(err)
+	}
+	return &Controller{
+		name:    c.name,
+		ctx:     c.ctx,
+		cancel:  c.cancel,
+		wg:      c.wg,
+		stats:   c.stats,
+		limiter: limiter,
+	}
+}
+
+// wait waits for the limiter of c if any, and returns whether the goroutine is permitted to run.
+// A rate limited goroutine is counted as started if it's permitted, or skipped otherwise.
+func (c *Controller) wait() bool {
+	if c.limiter == nil {
+		return true
+	}
+	if c.limiter.Wait(c.ctx) != nil {
+		atomic.AddInt64(&c.stats.skipped, 1)
+		return false
 	}
+	atomic.AddInt64(&c.stats.started, 1)
+	return true
 }
 
 // Shutdown cancels and waits for any goroutine under control.
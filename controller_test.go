@@ -56,3 +56,67 @@ func TestBackgroundController(t *testing.T) {
 		})
 	})
 }
+
+func TestControllerStats(t *testing.T) {
+	Convey("With test controller created", t, func(c C) {
+		const testRounds = 10
+		var (
+			ctrl    = NewController(context.Background(), t.Name())
+			release = make(chan interface{})
+		)
+		So(ctrl.Name(), ShouldEqual, t.Name())
+		for i := 0; i < testRounds; i++ {
+			ctrl.WithValue(hangingAroundKey1, i).Go(func(ctx context.Context) { <-release })
+		}
+		ctrl.GoWithRecover(func(ctx context.Context) { panic("oops") }, func(r interface{}) {})
+		ctrl.Go(func(ctx context.Context) {})
+		time.Sleep(100 * time.Millisecond)
+		So(ctrl.Stats(), ShouldResemble, ControllerStats{Started: testRounds + 2, Running: testRounds, Recovered: 1})
+
+		close(release)
+		ctrl.Wait()
+		stats := ctrl.Stats()
+		fmt.Println(stats)
+		So(stats, ShouldResemble, ControllerStats{Started: testRounds + 2, Running: 0, Recovered: 1})
+	})
+}
+
+// testLimiter permits an event for each value sent to it.
+type testLimiter chan struct{}
+
+func (l testLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-l:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestControllerWithRateLimit(t *testing.T) {
+	Convey("With rate limited controller created", t, func(c C) {
+		const testRounds = 10
+		var (
+			ctrl    = NewController(context.Background(), t.Name())
+			limiter = make(testLimiter)
+			limited = ctrl.WithRateLimit(limiter).WithValue(hangingAroundKey1, "limited")
+			ran     = make(chan interface{}, testRounds)
+		)
+		for i := 0; i < testRounds; i++ {
+			limited.Go(func(ctx context.Context) { ran <- ctx.Value(hangingAroundKey1) })
+		}
+		ctrl.Go(func(ctx context.Context) { ran <- nil }) // not limited
+		So(<-ran, ShouldBeNil)
+		for i := 0; i < testRounds/2; i++ {
+			limiter <- struct{}{}
+			So(<-ran, ShouldEqual, "limited")
+		}
+		So(ran, ShouldBeEmpty)
+		// Only permitted ones are started
+		So(ctrl.Stats(), ShouldResemble, ControllerStats{Started: testRounds/2 + 1, Running: testRounds / 2})
+
+		ctrl.Shutdown() // the rest are skipped
+		So(ran, ShouldBeEmpty)
+		So(ctrl.Stats(), ShouldResemble, ControllerStats{Started: testRounds/2 + 1, Skipped: testRounds / 2})
+	})
+}
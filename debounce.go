@@ -0,0 +1,341 @@
+package goproc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EdgeOption configures on which edges of an event burst Debouncer and Throttler fire.
+type EdgeOption func(e *edgeConfig)
+
+type edgeConfig struct {
+	leading  bool
+	trailing bool
+	maxWait  time.Duration
+}
+
+// WithLeadingEdge sets whether to fire on the leading edge of a burst, which is disabled by
+// default.
+func WithLeadingEdge(enabled bool) EdgeOption {
+	return func(e *edgeConfig) {
+		e.leading = enabled
+	}
+}
+
+// WithTrailingEdge sets whether to fire on the trailing edge of a burst, which is enabled by
+// default. A trailing fire only happens if there are events after the leading fire.
+func WithTrailingEdge(enabled bool) EdgeOption {
+	return func(e *edgeConfig) {
+		e.trailing = enabled
+	}
+}
+
+// WithMaxWait sets the max time that a Debouncer fire can be delayed by a continuous burst, after
+// which it fires as if the burst stops. It has no effect on Throttler.
+func WithMaxWait(d time.Duration) EdgeOption {
+	return func(e *edgeConfig) {
+		e.maxWait = d
+	}
+}
+
+// edgeTimer is the Deadliner scheduled for a key, which is keyed by the key so that rescheduling
+// replaces it. It's stale if the key state is replaced or rescheduled after it's sent.
+type edgeTimer struct {
+	key   string
+	state *edgeState
+	at    time.Time
+}
+
+func (t edgeTimer) Deadline() time.Time {
+	return t.at
+}
+
+func (t edgeTimer) Key() string {
+	return t.key
+}
+
+// edgeState is the state of a key in a burst.
+type edgeState struct {
+	value     interface{} // value of the last event
+	pending   bool        // whether there are events not fired
+	start     time.Time   // start of the burst, or the current max-wait window for Debouncer
+	last      time.Time   // time of the last event
+	scheduled time.Time   // deadline of the latest scheduled edgeTimer
+}
+
+// edgeRun is the running fire of a key, a later fire of the key waits for it to return.
+type edgeRun struct {
+	next   interface{} // value of the next fire
+	queued bool        // whether there's a next fire, which is coalesced with later ones
+}
+
+// edgeScheduler is the common part of Debouncer and Throttler, which schedules key timers by a
+// TimeoutChan, and fires on goroutines owned by a Controller. Fires of a key are serialized: a fire
+// due while the previous one is running waits for it to return, and is coalesced with later ones to
+// the value of the latest.
+type edgeScheduler struct {
+	edgeConfig
+	ctrl   *Controller
+	tc     *TimeoutChan
+	fn     func(key string, value interface{})
+	handle func(t edgeTimer, now time.Time) // called with mu held
+
+	mu      *sync.Mutex
+	closed  bool
+	states  map[string]*edgeState
+	running map[string]*edgeRun
+}
+
+func newEdgeScheduler(ctx context.Context, name string, resolution time.Duration,
+	fn func(key string, value interface{}), handle func(t edgeTimer, now time.Time), opts []EdgeOption) *edgeScheduler {
+	s := &edgeScheduler{
+		edgeConfig: edgeConfig{trailing: true},
+		ctrl:       NewController(ctx, name),
+		tc:         NewTimeoutChan(ctx, resolution, 0, WithDedup(DedupReplace, nil)),
+		fn:         fn,
+		handle:     handle,
+		mu:         &sync.Mutex{},
+		states:     make(map[string]*edgeState),
+		running:    make(map[string]*edgeRun),
+	}
+	for _, opt := range opts {
+		opt(&s.edgeConfig)
+	}
+	if !s.leading && !s.trailing {
+		panic("goproc: neither leading nor trailing edge is enabled")
+	}
+	s.ctrl.Go(s.timerProcess)
+	return s
+}
+
+// Cancel drops pending events of key, including a fire waiting for the running one, and returns
+// whether there are any.
+func (s *edgeScheduler) Cancel(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var dropped bool
+	if run, ok := s.running[key]; ok && run.queued {
+		run.next, run.queued = nil, false
+		dropped = true
+	}
+	state, ok := s.states[key]
+	if !ok {
+		return dropped
+	}
+	delete(s.states, key)
+	s.tc.removeKey(key)
+	return dropped || state.pending
+}
+
+// Len returns the number of keys in bursts.
+func (s *edgeScheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
+
+// Close drops pending events, including fires waiting for running ones, and waits for running
+// fires to return.
+func (s *edgeScheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.states = make(map[string]*edgeState)
+	for _, run := range s.running {
+		run.next, run.queued = nil, false
+	}
+	s.mu.Unlock()
+	s.tc.Shutdown()
+	s.ctrl.Wait() // timer process exits on closing of tc.Out
+}
+
+// fireLocked fires fn with key and value of state, or queues the fire if there's a running one of
+// key. It should be called with s.mu held.
+func (s *edgeScheduler) fireLocked(key string, state *edgeState) {
+	value := state.value
+	state.pending = false
+	state.value = nil
+	if run, ok := s.running[key]; ok {
+		run.next, run.queued = value, true
+		return
+	}
+	if s.ctrl.Die() {
+		return
+	}
+	s.running[key] = &edgeRun{}
+	s.ctrl.Go(func(ctx context.Context) {
+		s.run(ctx, key, value)
+	})
+}
+
+// run calls fn with key and value, and then with queued values of key until there's none.
+func (s *edgeScheduler) run(ctx context.Context, key string, value interface{}) {
+	for {
+		s.fn(key, value)
+		s.mu.Lock()
+		run := s.running[key]
+		if !run.queued || ctx.Err() != nil {
+			delete(s.running, key)
+			s.mu.Unlock()
+			return
+		}
+		value = run.next
+		run.next, run.queued = nil, false
+		s.mu.Unlock()
+	}
+}
+
+// scheduleLocked schedules a timer for key at at. It should be called with s.mu held.
+func (s *edgeScheduler) scheduleLocked(key string, state *edgeState, at time.Time) {
+	state.scheduled = at
+	s.tc.Push(edgeTimer{key: key, state: state, at: at}) // error is ignored as s is closed
+}
+
+func (s *edgeScheduler) timerProcess(ctx context.Context) {
+	for {
+		select {
+		case d, ok := <-s.tc.Out:
+			if !ok {
+				return
+			}
+			t := d.(edgeTimer)
+			s.mu.Lock()
+			if !s.closed && s.states[t.key] == t.state && t.state.scheduled.Equal(t.at) {
+				s.handle(t, time.Now())
+			}
+			s.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Debouncer fires a function for each key once after events of the key stop for a wait duration.
+// Timers of all keys are scheduled by a single TimeoutChan, and fires run on goroutines owned by
+// the Debouncer Controller - the function may be called concurrently for different keys, but
+// calls of a key are serialized in order.
+type Debouncer struct {
+	*edgeScheduler
+	wait time.Duration
+}
+
+// NewDebouncer creates a new Debouncer, which calls fn with key and the value of its last event
+// after events of the key stop for wait, within resolution.
+func NewDebouncer(ctx context.Context, resolution, wait time.Duration,
+	fn func(key string, value interface{}), opts ...EdgeOption) *Debouncer {
+	d := &Debouncer{wait: wait}
+	d.edgeScheduler = newEdgeScheduler(ctx, "Debouncer", resolution, fn, d.handleTimer, opts)
+	return d
+}
+
+// Trigger adds an event of key with value.
+func (d *Debouncer) Trigger(key string, value interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	now := time.Now()
+	state, ok := d.states[key]
+	if !ok {
+		state = &edgeState{start: now}
+		d.states[key] = state
+		state.value, state.last = value, now
+		if d.leading {
+			d.fireLocked(key, state)
+		} else {
+			state.pending = true
+		}
+		d.scheduleLocked(key, state, d.due(state))
+		return
+	}
+	state.value, state.last, state.pending = value, now, true
+	// The scheduled timer is earlier than the new due time, which reschedules on firing
+}
+
+// due returns the time to fire for state.
+func (d *Debouncer) due(state *edgeState) time.Time {
+	due := state.last.Add(d.wait)
+	if d.maxWait > 0 {
+		if max := state.start.Add(d.maxWait); max.Before(due) {
+			due = max
+		}
+	}
+	return due
+}
+
+func (d *Debouncer) handleTimer(t edgeTimer, now time.Time) {
+	state := t.state
+	if due := d.due(state); now.Before(due) {
+		d.scheduleLocked(t.key, state, due)
+		return
+	}
+	if now.Before(state.last.Add(d.wait)) {
+		// Max wait is reached in a continuous burst, fire and start a new window
+		if state.pending && d.trailing {
+			d.fireLocked(t.key, state)
+		}
+		state.start = now
+		d.scheduleLocked(t.key, state, d.due(state))
+		return
+	}
+	// Burst stops
+	if state.pending && d.trailing {
+		d.fireLocked(t.key, state)
+	}
+	delete(d.states, t.key)
+}
+
+// Throttler fires a function for each key at most once per interval. Timers of all keys are
+// scheduled by a single TimeoutChan, and fires run on goroutines owned by the Throttler
+// Controller - the function may be called concurrently for different keys, but calls of a key are
+// serialized in order, and a call running longer than interval delays the next one.
+type Throttler struct {
+	*edgeScheduler
+	interval time.Duration
+}
+
+// NewThrottler creates a new Throttler, which calls fn with key and the value of its last event at
+// most once per interval, within resolution.
+func NewThrottler(ctx context.Context, resolution, interval time.Duration,
+	fn func(key string, value interface{}), opts ...EdgeOption) *Throttler {
+	t := &Throttler{interval: interval}
+	t.edgeScheduler = newEdgeScheduler(ctx, "Throttler", resolution, fn, t.handleTimer, opts)
+	return t
+}
+
+// Trigger adds an event of key with value.
+func (t *Throttler) Trigger(key string, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	now := time.Now()
+	state, ok := t.states[key]
+	if !ok {
+		state = &edgeState{start: now}
+		t.states[key] = state
+		state.value, state.last = value, now
+		if t.leading {
+			t.fireLocked(key, state)
+		} else {
+			state.pending = true
+		}
+		t.scheduleLocked(key, state, now.Add(t.interval))
+		return
+	}
+	state.value, state.last, state.pending = value, now, true
+}
+
+func (t *Throttler) handleTimer(timer edgeTimer, now time.Time) {
+	state := timer.state
+	if !state.pending || !t.trailing {
+		delete(t.states, timer.key) // interval ends without events to fire
+		return
+	}
+	t.fireLocked(timer.key, state)
+	// The trailing fire starts a new interval
+	state.start = now
+	t.scheduleLocked(timer.key, state, now.Add(t.interval))
+}
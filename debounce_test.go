@@ -0,0 +1,207 @@
+package goproc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testFire struct {
+	key   string
+	value interface{}
+	at    time.Time
+}
+
+type testFireRecorder struct {
+	mu    sync.Mutex
+	fires []testFire
+}
+
+func (r *testFireRecorder) record(key string, value interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fires = append(r.fires, testFire{key, value, time.Now()})
+}
+
+func (r *testFireRecorder) values(key string) (values []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.fires {
+		if f.key == key {
+			values = append(values, f.value)
+		}
+	}
+	return
+}
+
+func (r *testFireRecorder) times(key string) (times []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.fires {
+		if f.key == key {
+			times = append(times, f.at)
+		}
+	}
+	return
+}
+
+func TestDebouncer(t *testing.T) {
+	Convey("With debouncer setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testWait       = 50 * time.Millisecond
+		)
+		recorder := &testFireRecorder{}
+		burst := func(d *Debouncer, key string, n int, interval time.Duration) time.Time {
+			for i := 1; i <= n; i++ {
+				d.Trigger(key, i)
+				if i < n {
+					time.Sleep(interval)
+				}
+			}
+			return time.Now()
+		}
+
+		Convey("Test trailing edge", func() {
+			d := NewDebouncer(context.Background(), testResolution, testWait, recorder.record)
+			d.Trigger("b", 1)
+			last := burst(d, "a", 5, testWait/2)
+			time.Sleep(testWait + 4*testResolution)
+			So(recorder.values("a"), ShouldResemble, []interface{}{5})
+			So(recorder.times("a")[0].Sub(last), ShouldBeGreaterThanOrEqualTo, testWait-testResolution)
+			So(recorder.values("b"), ShouldResemble, []interface{}{1})
+			So(d.Len(), ShouldEqual, 0)
+			d.Close()
+		})
+		Convey("Test leading and trailing edges", func() {
+			d := NewDebouncer(context.Background(), testResolution, testWait, recorder.record,
+				WithLeadingEdge(true))
+			burst(d, "a", 5, testWait/2)
+			d.Trigger("b", 1)
+			time.Sleep(testWait + 4*testResolution)
+			So(recorder.values("a"), ShouldResemble, []interface{}{1, 5})
+			So(recorder.values("b"), ShouldResemble, []interface{}{1}) // no trailing fire for single event
+			d.Close()
+		})
+		Convey("Test leading edge only", func() {
+			d := NewDebouncer(context.Background(), testResolution, testWait, recorder.record,
+				WithLeadingEdge(true), WithTrailingEdge(false))
+			burst(d, "a", 5, testWait/2)
+			time.Sleep(testWait + 4*testResolution)
+			burst(d, "a", 2, testWait/2)
+			time.Sleep(testWait + 4*testResolution)
+			So(recorder.values("a"), ShouldResemble, []interface{}{1, 1})
+			d.Close()
+		})
+		Convey("Test max wait", func() {
+			d := NewDebouncer(context.Background(), testResolution, testWait, recorder.record,
+				WithMaxWait(2*testWait))
+			burst(d, "a", 16, testWait/2) // lasts for 7.5 wait
+			time.Sleep(testWait + 4*testResolution)
+			times := recorder.times("a")
+			So(len(times), ShouldBeBetweenOrEqual, 4, 5)
+			for i := 1; i < len(times)-1; i++ {
+				So(times[i].Sub(times[i-1]), ShouldAlmostEqual, 2*testWait, 2*testResolution)
+			}
+			d.Close()
+		})
+		Convey("Test serialized fires", func() {
+			var (
+				mu       sync.Mutex
+				running  int
+				overlaps int
+			)
+			d := NewDebouncer(context.Background(), testResolution, testWait, func(key string, value interface{}) {
+				mu.Lock()
+				if running++; running > 1 {
+					overlaps++
+				}
+				mu.Unlock()
+				time.Sleep(3 * testWait) // blocks past the trailing edge
+				recorder.record(key, value)
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}, WithLeadingEdge(true))
+			burst(d, "a", 3, testWait/2)
+			time.Sleep(7 * testWait)
+			d.Close()
+			So(recorder.values("a"), ShouldResemble, []interface{}{1, 3}) // in order
+			So(overlaps, ShouldEqual, 0)
+		})
+		Convey("Test cancel and close", func() {
+			d := NewDebouncer(context.Background(), testResolution, testWait, recorder.record)
+			d.Trigger("a", 1)
+			d.Trigger("b", 1)
+			So(d.Cancel("a"), ShouldBeTrue)
+			So(d.Cancel("a"), ShouldBeFalse)
+			So(d.tc.Len(), ShouldEqual, 1) // timer of a is removed
+			for i := 0; i < 10; i++ {
+				d.Trigger("c", i)
+				d.Cancel("c")
+			}
+			So(d.tc.Len(), ShouldEqual, 1)
+			d.Close()
+			d.Trigger("c", 1)
+			time.Sleep(testWait + 4*testResolution)
+			So(recorder.fires, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestThrottler(t *testing.T) {
+	Convey("With throttler setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testInterval   = 50 * time.Millisecond
+		)
+		recorder := &testFireRecorder{}
+		burst := func(th *Throttler, key string, n int, interval time.Duration) {
+			for i := 1; i <= n; i++ {
+				th.Trigger(key, i)
+				time.Sleep(interval)
+			}
+		}
+
+		Convey("Test leading and trailing edges", func() {
+			th := NewThrottler(context.Background(), testResolution, testInterval, recorder.record,
+				WithLeadingEdge(true))
+			burst(th, "a", 20, testInterval/5) // lasts for 4 intervals
+			// The trailing fire starts a new interval
+			time.Sleep(2*testInterval + 4*testResolution)
+			So(th.Len(), ShouldEqual, 0)
+			th.Close()
+
+			values, times := recorder.values("a"), recorder.times("a")
+			So(len(values), ShouldBeBetweenOrEqual, 4, 6)
+			So(values[0], ShouldEqual, 1)
+			So(values[len(values)-1], ShouldEqual, 20)
+			for i := 1; i < len(times); i++ {
+				So(times[i].Sub(times[i-1]), ShouldBeGreaterThanOrEqualTo, testInterval-testResolution)
+			}
+		})
+		Convey("Test trailing edge only", func() {
+			th := NewThrottler(context.Background(), testResolution, testInterval, recorder.record)
+			th.Trigger("a", 1)
+			th.Trigger("a", 2)
+			So(recorder.values("a"), ShouldBeEmpty)
+			time.Sleep(testInterval + 4*testResolution)
+			So(recorder.values("a"), ShouldResemble, []interface{}{2})
+			th.Close()
+		})
+		Convey("Test leading edge only", func() {
+			th := NewThrottler(context.Background(), testResolution, testInterval, recorder.record,
+				WithLeadingEdge(true), WithTrailingEdge(false))
+			th.Trigger("a", 1)
+			th.Trigger("a", 2)
+			time.Sleep(testInterval + 4*testResolution)
+			th.Trigger("a", 3)
+			time.Sleep(4 * testResolution)
+			So(recorder.values("a"), ShouldResemble, []interface{}{1, 3})
+			th.Close()
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package goproc
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrEmptyQueue is returned when popping from an empty queue.
+var ErrEmptyQueue = errors.New("goproc: empty queue")
+
+type durableEntry struct {
+	id   uint64
+	item Prioritier
+}
+
+func (e durableEntry) Priority() int64 {
+	return e.item.Priority()
+}
+
+// DurablePriorityQueue is a priority queue persisted by a Journal, pushed elements survive
+// restarts until they are popped. DurablePriorityQueue is safe for concurrent use.
+type DurablePriorityQueue struct {
+	mu      sync.Mutex
+	pq      *PriorityQueue
+	journal *Journal
+}
+
+// OpenDurablePriorityQueue opens or creates a DurablePriorityQueue persisted in directory dir, and
+// rebuilds the heap from elements pushed but not popped in previous runs. The decoded items of
+// opts.Codec must implement Prioritier.
+func OpenDurablePriorityQueue(dir string, desc bool, opts JournalOptions) (*DurablePriorityQueue, error) {
+	journal, err := OpenJournal(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	entries := journal.Entries()
+	items := make([]Prioritier, len(entries))
+	for i, e := range entries {
+		item, ok := e.Item.(Prioritier)
+		if !ok {
+			journal.Close()
+			return nil, errors.New("goproc: journaled item is not a Prioritier")
+		}
+		items[i] = durableEntry{id: e.ID, item: item}
+	}
+	return &DurablePriorityQueue{
+		pq:      NewPriorityQueueFrom(desc, items),
+		journal: journal,
+	}, nil
+}
+
+// Len returns the number of elements in the queue.
+func (q *DurablePriorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Len()
+}
+
+// Push logs x and pushes it onto the queue.
+func (q *DurablePriorityQueue) Push(x Prioritier) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	id, err := q.journal.Append(x)
+	if err != nil {
+		return err
+	}
+	heap.Push(q.pq, durableEntry{id: id, item: x})
+	return nil
+}
+
+// Peek returns the top element of the queue, or false if the queue is empty.
+func (q *DurablePriorityQueue) Peek() (Prioritier, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pq.Len() == 0 {
+		return nil, false
+	}
+	return q.pq.Peek().(durableEntry).item, true
+}
+
+// Pop logs and removes the top element of the queue. The element stays in the queue if logging
+// fails. ErrEmptyQueue is returned if the queue is empty.
+func (q *DurablePriorityQueue) Pop() (Prioritier, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pq.Len() == 0 {
+		return nil, ErrEmptyQueue
+	}
+	if err := q.journal.Ack(q.pq.Peek().(durableEntry).id); err != nil {
+		return nil, err
+	}
+	return heap.Pop(q.pq).(durableEntry).item, nil
+}
+
+// Compact compacts the underlying journal.
+func (q *DurablePriorityQueue) Compact() error {
+	return q.journal.Compact()
+}
+
+// Sync commits the underlying journal to stable storage.
+func (q *DurablePriorityQueue) Sync() error {
+	return q.journal.Sync()
+}
+
+// Close closes the underlying journal, elements in the queue will be recovered on next open.
+func (q *DurablePriorityQueue) Close() error {
+	return q.journal.Close()
+}
@@ -0,0 +1,110 @@
+package goproc
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testDurableItem struct {
+	Name string
+	Prio int64
+}
+
+func (i testDurableItem) Priority() int64 {
+	return i.Prio
+}
+
+func newTestDurableItem() interface{} {
+	return &testDurableItem{}
+}
+
+func TestDurablePriorityQueue(t *testing.T) {
+	for _, codec := range []struct {
+		name  string
+		codec Codec
+	}{
+		{"gob", NewGobCodec(newTestDurableItem)},
+		{"json", NewJSONCodec(newTestDurableItem)},
+	} {
+		codec := codec
+		Convey("With durable priority queue setup using "+codec.name+" codec", t, func(c C) {
+			const testRounds = 100
+			dir, err := ioutil.TempDir("", "goproc-durable-pq")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			opts := JournalOptions{
+				Codec:            codec.codec,
+				Sync:             SyncNever,
+				CompactThreshold: testRounds / 2,
+			}
+			q, err := OpenDurablePriorityQueue(dir, false, opts)
+			So(err, ShouldBeNil)
+
+			expected := make([]int64, testRounds)
+			for i := range expected {
+				expected[i] = rand.Int63n(testRounds)
+				So(q.Push(testDurableItem{Name: "test", Prio: expected[i]}), ShouldBeNil)
+			}
+			sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+			for i := 0; i < testRounds/2; i++ {
+				item, err := q.Pop()
+				So(err, ShouldBeNil)
+				So(item.Priority(), ShouldEqual, expected[i])
+			}
+			expected = expected[testRounds/2:]
+
+			checkRecovered := func(q *DurablePriorityQueue) {
+				So(q.Len(), ShouldEqual, len(expected))
+				for _, p := range expected {
+					item, err := q.Pop()
+					So(err, ShouldBeNil)
+					So(item.Priority(), ShouldEqual, p)
+				}
+				_, err := q.Pop()
+				So(err, ShouldEqual, ErrEmptyQueue)
+			}
+
+			Convey("Test recover after close", func() {
+				So(q.Close(), ShouldBeNil)
+				q, err := OpenDurablePriorityQueue(dir, false, opts)
+				So(err, ShouldBeNil)
+				defer q.Close()
+				checkRecovered(q)
+			})
+
+			Convey("Test recover after crash", func() {
+				defer q.Close()
+				// Append a torn record to the log
+				f, err := os.OpenFile(filepath.Join(dir, journalLogFile), os.O_APPEND|os.O_WRONLY, 0644)
+				So(err, ShouldBeNil)
+				_, err = f.Write(encodeJournalRecord(journalOpAppend, 1<<20, []byte("torn"))[:10])
+				So(err, ShouldBeNil)
+				So(f.Close(), ShouldBeNil)
+
+				recovered, err := OpenDurablePriorityQueue(dir, false, opts)
+				So(err, ShouldBeNil)
+				defer recovered.Close()
+				checkRecovered(recovered)
+			})
+
+			Convey("Test recover after compaction", func() {
+				So(q.Compact(), ShouldBeNil)
+				info, err := os.Stat(filepath.Join(dir, journalLogFile))
+				So(err, ShouldBeNil)
+				So(info.Size(), ShouldEqual, 0)
+				So(q.Close(), ShouldBeNil)
+
+				q, err := OpenDurablePriorityQueue(dir, false, opts)
+				So(err, ShouldBeNil)
+				defer q.Close()
+				checkRecovered(q)
+			})
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package goproc
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Heap is the common interface of the priority queue implementations in this package.
+type Heap interface {
+	// Len returns the number of elements in the heap.
+	Len() int
+	// Peek returns the top element of the heap. User should ensure the heap is not empty before
+	// calling Peek.
+	Peek() interface{}
+	// Clear clears the heap and returns the number of cleared elements.
+	Clear() int
+	// Insert pushes x onto the heap.
+	Insert(x Prioritier)
+	// Extract removes and returns the top element of the heap. User should ensure the heap is
+	// not empty before calling Extract.
+	Extract() Prioritier
+}
+
+// HeapKind selects a Heap implementation for NewHeap.
+type HeapKind int
+
+// Heap implementations.
+const (
+	// HeapKindBinary selects PriorityQueue, the binary heap over container/heap.
+	HeapKindBinary HeapKind = iota
+	// HeapKindQuaternary selects a 4-ary DaryHeap, which has better cache locality for large
+	// heaps.
+	HeapKindQuaternary
+	// HeapKindPairing selects PairingHeap, which has cheap insert, merge and decrease-key.
+	HeapKindPairing
+)
+
+// String implements fmt.Stringer.
+func (k HeapKind) String() string {
+	switch k {
+	case HeapKindBinary:
+		return "Binary"
+	case HeapKindQuaternary:
+		return "Quaternary"
+	case HeapKindPairing:
+		return "Pairing"
+	default:
+		return fmt.Sprintf("HeapKind(%d)", int(k))
+	}
+}
+
+// NewHeap creates a new Heap of the given kind.
+func NewHeap(kind HeapKind, desc bool, size int) Heap {
+	switch kind {
+	case HeapKindBinary:
+		return NewPriorityQueue(desc, size)
+	case HeapKindQuaternary:
+		return NewDaryHeap(desc, 4, size)
+	case HeapKindPairing:
+		return NewPairingHeap(desc)
+	default:
+		panic(fmt.Sprintf("goproc: unknown heap kind %v", kind))
+	}
+}
+
+// Insert pushes x onto the priority queue, it's equivalent to heap.Push(q, x).
+func (q *PriorityQueue) Insert(x Prioritier) {
+	heap.Push(q, x)
+}
+
+// Extract removes and returns the top element of the priority queue, it's equivalent to
+// heap.Pop(q). User should ensure the queue is not empty before calling Extract.
+func (q *PriorityQueue) Extract() Prioritier {
+	return heap.Pop(q).(Prioritier)
+}
+
+type heapEntry struct {
+	priority int64
+	item     Prioritier
+}
+
+// DaryHeap is a d-ary heap implementation of priority queue. Priorities are read once on insert
+// and cached, so the elements should not change their priorities while in the heap.
+type DaryHeap struct {
+	heap []heapEntry
+	d    int
+	desc bool
+}
+
+// NewDaryHeap creates a new DaryHeap with d children per node.
+func NewDaryHeap(desc bool, d, size int) *DaryHeap {
+	if d < 2 {
+		panic("goproc: d-ary heap with d < 2")
+	}
+	return &DaryHeap{
+		heap: make([]heapEntry, 0, size),
+		d:    d,
+		desc: desc,
+	}
+}
+
+// Len returns the number of elements in the heap.
+func (h *DaryHeap) Len() int { return len(h.heap) }
+
+// Peek returns the top element of the heap. User should ensure the heap is not empty before
+// calling Peek.
+func (h *DaryHeap) Peek() interface{} {
+	return h.heap[0].item
+}
+
+// Clear clears the heap.
+func (h *DaryHeap) Clear() int {
+	l := len(h.heap)
+	for i := range h.heap {
+		h.heap[i].item = nil // release references
+	}
+	h.heap = h.heap[:0]
+	return l
+}
+
+// Insert pushes x onto the heap.
+func (h *DaryHeap) Insert(x Prioritier) {
+	h.heap = append(h.heap, heapEntry{priority: x.Priority(), item: x})
+	h.up(len(h.heap) - 1)
+}
+
+// Extract removes and returns the top element of the heap. User should ensure the heap is not
+// empty before calling Extract.
+func (h *DaryHeap) Extract() Prioritier {
+	l := len(h.heap) - 1
+	item := h.heap[0].item
+	h.heap[0] = h.heap[l]
+	h.heap[l].item = nil
+	h.heap = h.heap[:l]
+	if l > 0 {
+		h.down(0)
+	}
+	return item
+}
+
+func (h *DaryHeap) less(a, b int64) bool {
+	if h.desc {
+		return b < a
+	}
+	return a < b
+}
+
+func (h *DaryHeap) up(i int) {
+	e := h.heap[i]
+	for i > 0 {
+		p := (i - 1) / h.d
+		if !h.less(e.priority, h.heap[p].priority) {
+			break
+		}
+		h.heap[i] = h.heap[p]
+		i = p
+	}
+	h.heap[i] = e
+}
+
+func (h *DaryHeap) down(i int) {
+	var (
+		e = h.heap[i]
+		l = len(h.heap)
+	)
+	for {
+		first := h.d*i + 1
+		if first >= l {
+			break
+		}
+		last := first + h.d
+		if last > l {
+			last = l
+		}
+		m := first
+		for j := first + 1; j < last; j++ {
+			if h.less(h.heap[j].priority, h.heap[m].priority) {
+				m = j
+			}
+		}
+		if !h.less(h.heap[m].priority, e.priority) {
+			break
+		}
+		h.heap[i] = h.heap[m]
+		i = m
+	}
+	h.heap[i] = e
+}
+
+// PairingNode is a handle of an element inserted into a PairingHeap.
+type PairingNode struct {
+	priority int64
+	item     Prioritier
+	child    *PairingNode
+	sibling  *PairingNode
+	prev     *PairingNode // previous sibling, or parent for the first child
+}
+
+// Item returns the element held by n.
+func (n *PairingNode) Item() Prioritier { return n.item }
+
+// PairingHeap is a pairing heap implementation of priority queue, which supports O(1) insert and
+// merge, and amortized sub-logarithmic decrease-key through PairingNode handles. Priorities are
+// read on insert and cached until PairingHeap.Fix is called on the element's handle.
+type PairingHeap struct {
+	root *PairingNode
+	size int
+	desc bool
+}
+
+// NewPairingHeap creates a new PairingHeap.
+func NewPairingHeap(desc bool) *PairingHeap {
+	return &PairingHeap{desc: desc}
+}
+
+// Len returns the number of elements in the heap.
+func (h *PairingHeap) Len() int { return h.size }
+
+// Peek returns the top element of the heap. User should ensure the heap is not empty before
+// calling Peek.
+func (h *PairingHeap) Peek() interface{} {
+	return h.root.item
+}
+
+// Clear clears the heap.
+func (h *PairingHeap) Clear() int {
+	l := h.size
+	h.root = nil
+	h.size = 0
+	return l
+}
+
+// Insert pushes x onto the heap.
+func (h *PairingHeap) Insert(x Prioritier) {
+	h.InsertNode(x)
+}
+
+// InsertNode pushes x onto the heap and returns its handle, which can be used to fix or remove the
+// element later.
+func (h *PairingHeap) InsertNode(x Prioritier) *PairingNode {
+	n := &PairingNode{priority: x.Priority(), item: x}
+	h.root = h.meld(h.root, n)
+	h.size++
+	return n
+}
+
+// Extract removes and returns the top element of the heap. User should ensure the heap is not
+// empty before calling Extract.
+func (h *PairingHeap) Extract() Prioritier {
+	n := h.root
+	h.root = h.mergePairs(n.child)
+	n.child = nil
+	h.size--
+	return n.item
+}
+
+// Remove removes the element of handle n from the heap. User should ensure n is in the heap.
+func (h *PairingHeap) Remove(n *PairingNode) Prioritier {
+	if n == h.root {
+		return h.Extract()
+	}
+	h.cut(n)
+	h.root = h.meld(h.root, h.mergePairs(n.child))
+	n.child = nil
+	h.size--
+	return n.item
+}
+
+// Fix re-reads the priority of the element of handle n and re-establishes the heap ordering. It's
+// cheap when the element is moving towards the top, a.k.a. decrease-key.
+func (h *PairingHeap) Fix(n *PairingNode) {
+	old := n.priority
+	n.priority = n.item.Priority()
+	if !h.less(old, n.priority) { // not moving away from the top
+		if n != h.root {
+			h.cut(n)
+			h.root = h.meld(h.root, n)
+		}
+		return
+	}
+	h.Remove(n)
+	h.root = h.meld(h.root, n)
+	h.size++
+}
+
+// Merge absorbs all elements of other into h, other will be empty after merging. Existing
+// PairingNode handles of other remain valid in h.
+func (h *PairingHeap) Merge(other *PairingHeap) {
+	if other == h {
+		return
+	}
+	if h.desc != other.desc {
+		for other.size > 0 {
+			n := other.root
+			other.Extract()
+			h.root = h.meld(h.root, n)
+			h.size++
+		}
+		return
+	}
+	h.root = h.meld(h.root, other.root)
+	h.size += other.size
+	other.Clear()
+}
+
+func (h *PairingHeap) less(a, b int64) bool {
+	if h.desc {
+		return b < a
+	}
+	return a < b
+}
+
+// meld melds two detached trees and returns the new root.
+func (h *PairingHeap) meld(a, b *PairingNode) *PairingNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if h.less(b.priority, a.priority) {
+		a, b = b, a
+	}
+	b.sibling = a.child
+	if a.child != nil {
+		a.child.prev = b
+	}
+	b.prev = a
+	a.child = b
+	return a
+}
+
+// cut detaches the subtree rooted at n from its parent and siblings.
+func (h *PairingHeap) cut(n *PairingNode) {
+	if n.prev.child == n {
+		n.prev.child = n.sibling
+	} else {
+		n.prev.sibling = n.sibling
+	}
+	if n.sibling != nil {
+		n.sibling.prev = n.prev
+	}
+	n.prev = nil
+	n.sibling = nil
+}
+
+// mergePairs melds a list of sibling trees with the standard two-pass method.
+func (h *PairingHeap) mergePairs(first *PairingNode) *PairingNode {
+	// First pass: meld pairs from left to right, the results are chained in reverse order
+	var tail *PairingNode
+	for first != nil {
+		a, b := first, first.sibling
+		if b == nil {
+			a.prev = nil
+			a.sibling = tail
+			tail = a
+			break
+		}
+		first = b.sibling
+		a.prev, a.sibling, b.prev, b.sibling = nil, nil, nil, nil
+		m := h.meld(a, b)
+		m.sibling = tail
+		tail = m
+	}
+	if tail == nil {
+		return nil
+	}
+	// Second pass: meld from right to left
+	root := tail
+	tail, root.sibling = tail.sibling, nil
+	for tail != nil {
+		next := tail.sibling
+		tail.sibling = nil
+		root = h.meld(root, tail)
+		tail = next
+	}
+	return root
+}
@@ -0,0 +1,197 @@
+package goproc
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testHeapKinds = []HeapKind{HeapKindBinary, HeapKindQuaternary, HeapKindPairing}
+
+type testMutablePrioritier struct {
+	priority int64
+}
+
+func (p *testMutablePrioritier) Priority() int64 {
+	return p.priority
+}
+
+func TestHeap(t *testing.T) {
+	for _, kind := range testHeapKinds {
+		kind := kind
+		Convey(fmt.Sprintf("With %v heap setup", kind), t, func(c C) {
+			const testRounds = 1000
+			inList := make([]int64, testRounds)
+			for i := range inList {
+				inList[i] = rand.Int63n(testRounds / 2) // with duplicates
+			}
+
+			Convey("Test ascending order", func() {
+				h := NewHeap(kind, false, 0)
+				for _, p := range inList {
+					h.Insert(testPrioritier(p))
+				}
+				sort.Slice(inList, func(i, j int) bool { return inList[i] < inList[j] })
+				So(h.Len(), ShouldEqual, testRounds)
+				for _, expected := range inList {
+					So(h.Peek().(Prioritier).Priority(), ShouldEqual, expected)
+					So(h.Extract().Priority(), ShouldEqual, expected)
+				}
+				So(h.Len(), ShouldEqual, 0)
+			})
+
+			Convey("Test descending order", func() {
+				h := NewHeap(kind, true, 0)
+				for _, p := range inList {
+					h.Insert(testPrioritier(p))
+				}
+				sort.Slice(inList, func(i, j int) bool { return inList[j] < inList[i] })
+				for _, expected := range inList {
+					So(h.Extract().Priority(), ShouldEqual, expected)
+				}
+				So(h.Len(), ShouldEqual, 0)
+			})
+
+			Convey("Test clear", func() {
+				h := NewHeap(kind, false, 0)
+				for _, p := range inList {
+					h.Insert(testPrioritier(p))
+				}
+				So(h.Clear(), ShouldEqual, testRounds)
+				So(h.Len(), ShouldEqual, 0)
+			})
+		})
+	}
+}
+
+func TestPairingHeap(t *testing.T) {
+	Convey("With pairing heap setup", t, func(c C) {
+		const testRounds = 1000
+		var (
+			h     = NewPairingHeap(false)
+			nodes = make([]*PairingNode, testRounds)
+		)
+		for i := range nodes {
+			nodes[i] = h.InsertNode(&testMutablePrioritier{priority: rand.Int63n(testRounds)})
+		}
+		drain := func(h *PairingHeap) []int64 {
+			var out []int64
+			for h.Len() > 0 {
+				out = append(out, h.Extract().Priority())
+			}
+			return out
+		}
+
+		Convey("Test fix", func() {
+			for i, n := range nodes {
+				p := n.Item().(*testMutablePrioritier)
+				if i%2 == 0 {
+					p.priority -= rand.Int63n(testRounds) // decrease-key
+				} else {
+					p.priority += rand.Int63n(testRounds)
+				}
+				h.Fix(n)
+			}
+			out := drain(h)
+			So(len(out), ShouldEqual, testRounds)
+			So(sort.SliceIsSorted(out, func(i, j int) bool { return out[i] < out[j] }), ShouldBeTrue)
+		})
+
+		Convey("Test remove", func() {
+			for i := 0; i < testRounds; i += 2 {
+				h.Remove(nodes[i])
+			}
+			out := drain(h)
+			So(len(out), ShouldEqual, testRounds/2)
+			So(sort.SliceIsSorted(out, func(i, j int) bool { return out[i] < out[j] }), ShouldBeTrue)
+		})
+
+		Convey("Test merge", func() {
+			other := NewPairingHeap(false)
+			for i := 0; i < testRounds; i++ {
+				other.Insert(testPrioritier(rand.Int63n(testRounds)))
+			}
+			h.Merge(other)
+			So(other.Len(), ShouldEqual, 0)
+			out := drain(h)
+			So(len(out), ShouldEqual, 2*testRounds)
+			So(sort.SliceIsSorted(out, func(i, j int) bool { return out[i] < out[j] }), ShouldBeTrue)
+		})
+	})
+}
+
+var testBenchmarkHeapSizes = []int{10, 1000, 100000, 10000000}
+
+func benchmarkHeaps(b *testing.B, f func(b *testing.B, h Heap, items []Prioritier)) {
+	for _, kind := range testHeapKinds {
+		for _, n := range testBenchmarkHeapSizes {
+			items := make([]Prioritier, n)
+			for i := range items {
+				items[i] = testPrioritier(rand.Int63())
+			}
+			b.Run(fmt.Sprintf("%v/n=%d", kind, n), func(b *testing.B) {
+				h := NewHeap(kind, false, n)
+				b.ReportAllocs()
+				f(b, h, items)
+			})
+		}
+	}
+}
+
+func BenchmarkHeapInsert(b *testing.B) {
+	benchmarkHeaps(b, func(b *testing.B, h Heap, items []Prioritier) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if h.Len() == len(items) {
+				b.StopTimer()
+				h.Clear()
+				b.StartTimer()
+			}
+			h.Insert(items[h.Len()])
+		}
+	})
+}
+
+func BenchmarkHeapExtract(b *testing.B) {
+	benchmarkHeaps(b, func(b *testing.B, h Heap, items []Prioritier) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if h.Len() == 0 {
+				b.StopTimer()
+				for _, item := range items {
+					h.Insert(item)
+				}
+				b.StartTimer()
+			}
+			h.Extract()
+		}
+	})
+}
+
+func BenchmarkHeapInsertExtract(b *testing.B) {
+	benchmarkHeaps(b, func(b *testing.B, h Heap, items []Prioritier) {
+		for _, item := range items {
+			h.Insert(item)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Insert(items[i%len(items)])
+			h.Extract()
+		}
+	})
+}
+
+func BenchmarkHeapPeek(b *testing.B) {
+	benchmarkHeaps(b, func(b *testing.B, h Heap, items []Prioritier) {
+		for _, item := range items {
+			h.Insert(item)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = h.Peek()
+		}
+	})
+}
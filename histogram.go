@@ -0,0 +1,131 @@
+package goproc
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultLatencyBounds are the default histogram bucket upper bounds for latencies, from 10μs to
+// 10s.
+var DefaultLatencyBounds = []time.Duration{
+	10 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 500 * time.Microsecond,
+	1 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond,
+	1 * time.Second, 5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a histogram of durations with fixed buckets. Histogram is not safe for concurrent
+// use.
+type Histogram struct {
+	bounds []time.Duration
+	counts []int
+	count  int
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram creates a new Histogram with the given ascending bucket upper bounds, an extra
+// bucket is added for durations above the last bound.
+func NewHistogram(bounds []time.Duration) *Histogram {
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			panic("goproc: histogram bounds not in ascending order")
+		}
+	}
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]int, len(bounds)+1),
+	}
+}
+
+// Observe adds a duration to the histogram. Negative durations are recorded as 0.
+func (h *Histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if h.count == 0 || d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Snapshot returns a copy of current histogram data.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	counts := make([]int, len(h.counts))
+	copy(counts, h.counts)
+	return HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: counts,
+		Count:  h.count,
+		Sum:    h.sum,
+		Min:    h.min,
+		Max:    h.max,
+	}
+}
+
+// HistogramSnapshot contains histogram data returned from Histogram.Snapshot().
+type HistogramSnapshot struct {
+	// Bounds are the bucket upper bounds.
+	Bounds []time.Duration
+	// Counts are the non-cumulative bucket counts, the last one counts durations above the last
+	// bound.
+	Counts []int
+	Count  int
+	Sum    time.Duration
+	Min    time.Duration
+	Max    time.Duration
+}
+
+// Mean returns the mean duration, or 0 for an empty histogram.
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Percentile returns an estimation of the p-th percentile, 0 <= p <= 100, by linear
+// interpolation in the bucket that the percentile falls in.
+func (s HistogramSnapshot) Percentile(p float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	var (
+		rank = p / 100 * float64(s.Count)
+		cum  float64
+	)
+	for i, n := range s.Counts {
+		if n == 0 || cum+float64(n) < rank {
+			cum += float64(n)
+			continue
+		}
+		lower, upper := s.Min, s.Max
+		if i > 0 && s.Bounds[i-1] > lower {
+			lower = s.Bounds[i-1]
+		}
+		if i < len(s.Bounds) && s.Bounds[i] < upper {
+			upper = s.Bounds[i]
+		}
+		return lower + time.Duration(float64(upper-lower)*(rank-cum)/float64(n))
+	}
+	return s.Max
+}
+
+// String implements fmt.Stringer.
+func (s HistogramSnapshot) String() string {
+	return fmt.Sprintf("Count=%d Mean=%v P50=%v P99=%v Max=%v",
+		s.Count, s.Mean(), s.Percentile(50), s.Percentile(99), s.Max)
+}
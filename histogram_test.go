@@ -0,0 +1,38 @@
+package goproc
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("With histogram setup", t, func(c C) {
+		h := NewHistogram([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond})
+		So(h.Snapshot().Percentile(50), ShouldEqual, 0)
+
+		for i := 1; i <= 100; i++ {
+			h.Observe(time.Duration(i) * 500 * time.Microsecond) // 0.5ms to 50ms
+		}
+		s := h.Snapshot()
+		So(s.Count, ShouldEqual, 100)
+		So(s.Counts, ShouldResemble, []int{20, 20, 40, 20})
+		So(s.Min, ShouldEqual, 500*time.Microsecond)
+		So(s.Max, ShouldEqual, 50*time.Millisecond)
+		So(s.Mean(), ShouldEqual, 25250*time.Microsecond)
+
+		So(s.Percentile(0), ShouldEqual, s.Min)
+		So(s.Percentile(20), ShouldEqual, 10*time.Millisecond)
+		So(s.Percentile(30), ShouldEqual, 15*time.Millisecond)
+		So(s.Percentile(60), ShouldEqual, 30*time.Millisecond)
+		So(s.Percentile(90), ShouldEqual, 45*time.Millisecond)
+		So(s.Percentile(100), ShouldEqual, s.Max)
+
+		h.Observe(-time.Second) // recorded as 0
+		s = h.Snapshot()
+		So(s.Counts[0], ShouldEqual, 21)
+		So(s.Min, ShouldEqual, 0)
+		So(s.Sum, ShouldEqual, 100*25250*time.Microsecond)
+	})
+}
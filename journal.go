@@ -0,0 +1,436 @@
+package goproc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	journalLogFile      = "wal"
+	journalSnapshotFile = "snapshot"
+
+	journalOpAppend byte = 1
+	journalOpAck    byte = 2
+
+	journalHeaderSize    = 8 // payload length and checksum
+	journalMaxRecordSize = 1 << 30
+
+	defaultJournalCompactThreshold = 4096
+)
+
+// ErrJournalClosed is returned when operating on a closed Journal.
+var ErrJournalClosed = errors.New("goproc: journal closed")
+
+var errJournalCorrupted = errors.New("goproc: journal record corrupted")
+
+// SyncPolicy defines when Journal calls fsync on its log file.
+type SyncPolicy int
+
+const (
+	// SyncAlways calls fsync after every record, which is the most durable and the slowest.
+	SyncAlways SyncPolicy = iota
+	// SyncPeriodic calls fsync on appending a record if JournalOptions.SyncInterval has passed
+	// since the last fsync. Records appended in the interval survive process crashes, but may be
+	// lost on power failures.
+	SyncPeriodic
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// JournalOptions contains options for OpenJournal.
+type JournalOptions struct {
+	// Codec encodes and decodes journaled items, it's required.
+	Codec Codec
+	// Sync is the fsync policy of the log file.
+	Sync SyncPolicy
+	// SyncInterval is the fsync interval with SyncPeriodic policy.
+	SyncInterval time.Duration
+	// CompactThreshold is the number of log records that triggers a compaction, which rewrites
+	// pending items into a snapshot and truncates the log. 0 means a default of 4096. Triggered
+	// compactions are best-effort: a failed one doesn't fail the triggering Append or Ack, whose
+	// record is already written, and is retried by the next record - see Journal.CompactErr.
+	CompactThreshold int
+}
+
+// JournalEntry is a pending item in Journal.
+type JournalEntry struct {
+	ID   uint64
+	Item interface{}
+}
+
+// Journal is a local write-ahead log of appended and acknowledged items, which keeps track of
+// pending - appended but not yet acknowledged - items and recovers them on open. Journal is safe
+// for concurrent use.
+type Journal struct {
+	dir  string
+	opts JournalOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	pending  map[uint64]interface{}
+	nextID   uint64
+	records  int
+	lastSync time.Time
+	// compactErr is the error of the last triggered compaction.
+	compactErr error
+}
+
+// OpenJournal opens or creates a Journal in directory dir, pending items from previous runs are
+// recovered and can be listed with Journal.Entries.
+func OpenJournal(dir string, opts JournalOptions) (*Journal, error) {
+	if opts.Codec == nil {
+		return nil, errors.New("goproc: journal codec is required")
+	}
+	if opts.CompactThreshold <= 0 {
+		opts.CompactThreshold = defaultJournalCompactThreshold
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		dir:      dir,
+		opts:     opts,
+		pending:  make(map[uint64]interface{}),
+		nextID:   1,
+		lastSync: time.Now(),
+	}
+	if err := j.replaySnapshot(); err != nil {
+		return nil, err
+	}
+	if err := j.replayLog(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append appends item to the journal and returns its ID for acknowledging. It returns an error only
+// if the record is not written.
+func (j *Journal) Append(item interface{}) (uint64, error) {
+	data, err := j.opts.Codec.Encode(item)
+	if err != nil {
+		return 0, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return 0, ErrJournalClosed
+	}
+	id := j.nextID
+	if err := j.write(journalOpAppend, id, data); err != nil {
+		return 0, err
+	}
+	j.nextID++
+	j.pending[id] = item
+	j.maybeCompact()
+	return id, nil
+}
+
+// Ack acknowledges the item of id, which will not be recovered any more. Acknowledging an unknown
+// ID is a no-op.
+func (j *Journal) Ack(id uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	if err := j.write(journalOpAck, id, nil); err != nil {
+		return err
+	}
+	delete(j.pending, id)
+	j.maybeCompact()
+	return nil
+}
+
+// Len returns the number of pending items.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending)
+}
+
+// Entries returns pending items in appending order.
+func (j *Journal) Entries() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entries()
+}
+
+// Compact rewrites pending items into a snapshot and truncates the log.
+func (j *Journal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+	return j.compact()
+}
+
+// CompactErr returns the error of the last compaction triggered by CompactThreshold, or nil if it
+// succeeded.
+func (j *Journal) CompactErr() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.compactErr
+}
+
+// Sync commits the log file to stable storage.
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+	j.lastSync = time.Now()
+	return j.file.Sync()
+}
+
+// Close syncs and closes the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrJournalClosed
+	}
+	err := j.file.Sync()
+	if cerr := j.file.Close(); err == nil {
+		err = cerr
+	}
+	j.file = nil
+	return err
+}
+
+func (j *Journal) entries() []JournalEntry {
+	entries := make([]JournalEntry, 0, len(j.pending))
+	for id, item := range j.pending {
+		entries = append(entries, JournalEntry{ID: id, Item: item})
+	}
+	sort.Slice(entries, func(i, k int) bool { return entries[i].ID < entries[k].ID })
+	return entries
+}
+
+func (j *Journal) path(name string) string {
+	return filepath.Join(j.dir, name)
+}
+
+func (j *Journal) write(op byte, id uint64, data []byte) error {
+	if _, err := j.file.Write(encodeJournalRecord(op, id, data)); err != nil {
+		return err
+	}
+	j.records++
+	switch j.opts.Sync {
+	case SyncAlways:
+		return j.file.Sync()
+	case SyncPeriodic:
+		if now := time.Now(); now.Sub(j.lastSync) >= j.opts.SyncInterval {
+			j.lastSync = now
+			return j.file.Sync()
+		}
+	}
+	return nil
+}
+
+// maybeCompact compacts the journal if CompactThreshold is reached, and keeps the error for
+// CompactErr.
+func (j *Journal) maybeCompact() {
+	if j.records < j.opts.CompactThreshold || j.records <= len(j.pending) {
+		return
+	}
+	j.compactErr = j.compact()
+}
+
+// compact writes pending items into a temporary snapshot file, replaces the snapshot with it and
+// truncates the log. Recovering is idempotent for records in both the snapshot and the log, so a
+// crash between the replacement and the truncation is harmless.
+func (j *Journal) compact() error {
+	tmp := j.path(journalSnapshotFile + ".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range j.entries() {
+		data, err := j.opts.Codec.Encode(e.Item)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := w.Write(encodeJournalRecord(journalOpAppend, e.ID, data)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path(journalSnapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.records = 0
+	j.lastSync = time.Now()
+	return j.file.Sync()
+}
+
+func (j *Journal) apply(op byte, id uint64, data []byte) error {
+	switch op {
+	case journalOpAppend:
+		if _, ok := j.pending[id]; !ok {
+			item, err := j.opts.Codec.Decode(data)
+			if err != nil {
+				return err
+			}
+			j.pending[id] = item
+		}
+		if id >= j.nextID {
+			j.nextID = id + 1
+		}
+	case journalOpAck:
+		delete(j.pending, id)
+	default:
+		return errJournalCorrupted
+	}
+	return nil
+}
+
+func (j *Journal) replaySnapshot() error {
+	f, err := os.Open(j.path(journalSnapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		op, id, data, _, err := readJournalRecord(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			// The snapshot is replaced atomically, any broken record is a real corruption
+			return fmt.Errorf("goproc: read journal snapshot: %w", err)
+		}
+		if err := j.apply(op, id, data); err != nil {
+			return err
+		}
+	}
+}
+
+func (j *Journal) replayLog() error {
+	f, err := os.OpenFile(j.path(journalLogFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		r      = bufio.NewReader(f)
+		offset int64
+	)
+	for {
+		op, id, data, n, err := readJournalRecord(r)
+		if err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF || err == errJournalCorrupted {
+			// A torn write at the tail from a crash, discard it
+			if err := f.Truncate(offset); err != nil {
+				f.Close()
+				return err
+			}
+			break
+		} else if err != nil {
+			f.Close()
+			return err
+		}
+		if err := j.apply(op, id, data); err != nil {
+			f.Close()
+			return err
+		}
+		offset += int64(n)
+		j.records++
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	j.file = f
+	return nil
+}
+
+func encodeJournalRecord(op byte, id uint64, data []byte) []byte {
+	payloadSize := 1 + binary.MaxVarintLen64 + len(data)
+	buf := make([]byte, journalHeaderSize+payloadSize)
+	payload := buf[journalHeaderSize:]
+	payload[0] = op
+	n := 1 + binary.PutUvarint(payload[1:], id)
+	n += copy(payload[n:], data)
+	payload = payload[:n]
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(n))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	return buf[:journalHeaderSize+n]
+}
+
+// readJournalRecord reads a record from r and returns its content and size in bytes. It returns
+// io.EOF only if there is no more record.
+func readJournalRecord(r io.Reader) (op byte, id uint64, data []byte, n int, err error) {
+	var header [journalHeaderSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size < 2 || size > journalMaxRecordSize {
+		err = errJournalCorrupted
+		return
+	}
+	payload := make([]byte, size)
+	if _, err = io.ReadFull(r, payload); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+		return
+	} else if err != nil {
+		return
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		err = errJournalCorrupted
+		return
+	}
+	id, m := binary.Uvarint(payload[1:])
+	if m <= 0 {
+		err = errJournalCorrupted
+		return
+	}
+	return payload[0], id, payload[1+m:], journalHeaderSize + int(size), nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,117 @@
+package goproc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJournal(t *testing.T) {
+	Convey("With journal setup", t, func(c C) {
+		const testThreshold = 10
+		dir, err := ioutil.TempDir("", "goproc-journal")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		opts := JournalOptions{
+			Codec:            NewJSONCodec(newTestDurableItem),
+			Sync:             SyncNever,
+			CompactThreshold: testThreshold,
+		}
+		j, err := OpenJournal(dir, opts)
+		So(err, ShouldBeNil)
+		defer func() { j.Close() }()
+		appendItems := func(n int) (ids []uint64) {
+			for i := 0; i < n; i++ {
+				id, err := j.Append(testDurableItem{Name: "test", Prio: int64(i)})
+				So(err, ShouldBeNil)
+				ids = append(ids, id)
+			}
+			return
+		}
+		logSize := func() int64 {
+			info, err := os.Stat(filepath.Join(dir, journalLogFile))
+			So(err, ShouldBeNil)
+			return info.Size()
+		}
+
+		Convey("Test periodic sync", func() {
+			const testInterval = 50 * time.Millisecond
+			So(j.Close(), ShouldBeNil)
+			opts.Sync, opts.SyncInterval = SyncPeriodic, testInterval
+			j, err = OpenJournal(dir, opts)
+			So(err, ShouldBeNil)
+			lastSync := j.lastSync
+			appendItems(1)
+			So(j.lastSync, ShouldEqual, lastSync) // within the interval
+			time.Sleep(testInterval)
+			appendItems(1)
+			So(j.lastSync, ShouldHappenAfter, lastSync)
+		})
+		Convey("Test compaction threshold", func() {
+			ids := appendItems(testThreshold)
+			So(logSize(), ShouldBeGreaterThan, 0) // all records are pending
+			for _, id := range ids[:testThreshold/2] {
+				So(j.Ack(id), ShouldBeNil)
+			}
+			So(j.records, ShouldBeLessThan, testThreshold)
+			So(j.CompactErr(), ShouldBeNil)
+			So(j.Close(), ShouldBeNil)
+
+			j, err = OpenJournal(dir, opts)
+			So(err, ShouldBeNil)
+			entries := j.Entries()
+			So(len(entries), ShouldEqual, testThreshold/2)
+			for i, e := range entries {
+				So(e.ID, ShouldEqual, ids[testThreshold/2+i])
+			}
+		})
+		Convey("Test compaction failure", func() {
+			// A directory in place of the temporary snapshot fails compactions
+			tmp := filepath.Join(dir, journalSnapshotFile+".tmp")
+			So(os.Mkdir(tmp, 0755), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(tmp, "blocker"), nil, 0644), ShouldBeNil)
+			ids := appendItems(testThreshold)
+			for _, id := range ids[:testThreshold/2] {
+				So(j.Ack(id), ShouldBeNil) // the record is written regardless of the failure
+			}
+			So(j.CompactErr(), ShouldNotBeNil)
+			So(j.Len(), ShouldEqual, testThreshold/2)
+
+			So(os.RemoveAll(tmp), ShouldBeNil)
+			appendItems(1) // retried by the next record
+			So(j.CompactErr(), ShouldBeNil)
+			So(logSize(), ShouldEqual, 0)
+			So(j.Len(), ShouldEqual, testThreshold/2+1)
+		})
+		Convey("Test reopen after all acknowledged", func() {
+			for _, id := range appendItems(3) {
+				So(j.Ack(id), ShouldBeNil)
+			}
+			So(j.Close(), ShouldBeNil)
+
+			j, err = OpenJournal(dir, opts)
+			So(err, ShouldBeNil)
+			So(j.Len(), ShouldEqual, 0)
+			So(j.Entries(), ShouldBeEmpty)
+			So(appendItems(1)[0], ShouldEqual, 4) // IDs in the log are not reused
+		})
+		Convey("Test acknowledging unknown ID", func() {
+			appendItems(1)
+			size := logSize()
+			So(j.Ack(1<<20), ShouldBeNil)
+			So(logSize(), ShouldEqual, size) // nothing written
+			So(j.Len(), ShouldEqual, 1)
+		})
+		Convey("Test closed journal", func() {
+			So(j.Close(), ShouldBeNil)
+			_, err := j.Append(testDurableItem{})
+			So(err, ShouldEqual, ErrJournalClosed)
+			So(j.Ack(1), ShouldEqual, ErrJournalClosed)
+			So(j.Close(), ShouldEqual, ErrJournalClosed)
+		})
+	})
+}
@@ -0,0 +1,226 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLeaseHeld is returned when granting a lease which is held by an active lease.
+	ErrLeaseHeld = errors.New("goproc: lease is held")
+	// ErrLeaseNotFound is returned when renewing or revoking a lease which is expired, revoked or
+	// granted with another fencing token.
+	ErrLeaseNotFound = errors.New("goproc: lease not found")
+)
+
+// Lease is a lease of a named resource granted by LeaseManager.
+type Lease struct {
+	Name   string
+	Holder string
+	// Token is the fencing token of the lease, which strictly increases on each grant of a
+	// LeaseManager. Resources guarded by leases should reject requests with tokens lower than the
+	// latest one they have seen.
+	Token   uint64
+	Granted time.Time
+	Expiry  time.Time
+}
+
+// String implements fmt.Stringer.
+func (l Lease) String() string {
+	return fmt.Sprintf("Lease: Name=%s Holder=%s Token=%d Expiry=%s",
+		l.Name, l.Holder, l.Token, l.Expiry.Format(time.RFC3339Nano))
+}
+
+// leaseExpiry is the Deadliner scheduled for lease expiry, which is keyed by lease name so that
+// renewing a lease replaces its scheduled expiry.
+type leaseExpiry struct {
+	name  string
+	token uint64
+	at    time.Time
+}
+
+func (e leaseExpiry) Deadline() time.Time {
+	return e.at
+}
+
+func (e leaseExpiry) Key() string {
+	return e.name
+}
+
+// LeaseManager grants leases of named resources with TTLs, which expire unless renewed in time.
+// Expiries are scheduled by a TimeoutChan and handled by a goroutine owned by the manager
+// Controller.
+type LeaseManager struct {
+	ctrl    *Controller
+	tc      *TimeoutChan
+	expired chan<- Lease
+	wake    chan interface{}
+
+	mu      *sync.Mutex
+	leases  map[string]*Lease
+	token   uint64
+	pending []Lease // expired leases to be notified by expire process
+}
+
+// NewLeaseManager creates a new LeaseManager, expiries are handled within resolution. Expired
+// leases are sent to the optional expired channel, which is not closed by LeaseManager.
+func NewLeaseManager(ctx context.Context, resolution time.Duration, expired chan<- Lease) *LeaseManager {
+	m := &LeaseManager{
+		ctrl:    NewController(ctx, "LeaseManager"),
+		tc:      NewTimeoutChan(ctx, resolution, 0, WithDedup(DedupReplace, nil)),
+		expired: expired,
+		wake:    make(chan interface{}, 1),
+		mu:      &sync.Mutex{},
+		leases:  make(map[string]*Lease),
+	}
+	m.ctrl.Go(m.expireProcess)
+	return m
+}
+
+// Grant grants the lease of name to holder with ttl, it returns ErrLeaseHeld if the lease is held
+// by an active lease - even of the same holder, which should renew it instead.
+func (m *LeaseManager) Grant(name, holder string, ttl time.Duration) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if l, ok := m.leases[name]; ok {
+		if now.Before(l.Expiry) {
+			return Lease{}, ErrLeaseHeld
+		}
+		m.expireLocked(l) // expired but not handled yet
+	}
+	m.token++
+	l := &Lease{Name: name, Holder: holder, Token: m.token, Granted: now, Expiry: now.Add(ttl)}
+	m.leases[name] = l
+	m.schedule(l)
+	return *l, nil
+}
+
+// Renew renews the lease of name with fencing token to expire after ttl from now, which can also
+// shorten the lease. It returns ErrLeaseNotFound if the lease isn't active.
+func (m *LeaseManager) Renew(name string, token uint64, ttl time.Duration) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.active(name, token)
+	if err != nil {
+		return Lease{}, err
+	}
+	l.Expiry = time.Now().Add(ttl)
+	m.schedule(l)
+	return *l, nil
+}
+
+// Revoke revokes the lease of name with fencing token, it returns ErrLeaseNotFound if the lease
+// isn't active. Revoked leases are not sent as expired.
+func (m *LeaseManager) Revoke(name string, token uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.active(name, token); err != nil {
+		return err
+	}
+	delete(m.leases, name)
+	m.tc.removeKey(leaseExpiry{name: name}.Key())
+	return nil
+}
+
+// Get returns the active lease of name, or false if there's none.
+func (m *LeaseManager) Get(name string) (Lease, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.leases[name]; ok && time.Now().Before(l.Expiry) {
+		return *l, true
+	}
+	return Lease{}, false
+}
+
+// Active returns all active leases sorted by name.
+func (m *LeaseManager) Active() []Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var (
+		now    = time.Now()
+		leases = make([]Lease, 0, len(m.leases))
+	)
+	for _, l := range m.leases {
+		if now.Before(l.Expiry) {
+			leases = append(leases, *l)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].Name < leases[j].Name })
+	return leases
+}
+
+// Close stops handling expiries and shuts down the manager goroutines.
+func (m *LeaseManager) Close() {
+	m.ctrl.Shutdown()
+	m.tc.Shutdown()
+}
+
+// active returns the active lease of name with token. It should be called with m.mu held.
+func (m *LeaseManager) active(name string, token uint64) (*Lease, error) {
+	l, ok := m.leases[name]
+	if !ok || l.Token != token || !time.Now().Before(l.Expiry) {
+		return nil, ErrLeaseNotFound
+	}
+	return l, nil
+}
+
+// schedule schedules the expiry of l, which replaces any scheduled one of the same name. It should
+// be called with m.mu held.
+func (m *LeaseManager) schedule(l *Lease) {
+	m.tc.Push(leaseExpiry{name: l.Name, token: l.Token, at: l.Expiry}) // error is ignored as manager is closed
+}
+
+// expireLocked removes expired lease l, and passes it to expire process for notifying. It should
+// be called with m.mu held.
+func (m *LeaseManager) expireLocked(l *Lease) {
+	delete(m.leases, l.Name)
+	if m.expired != nil {
+		m.pending = append(m.pending, *l)
+		signal(m.wake)
+	}
+}
+
+func (m *LeaseManager) expireProcess(ctx context.Context) {
+	for {
+		var expired []Lease
+		select {
+		case d := <-m.tc.Out:
+			if lease, ok := m.handle(d.(leaseExpiry)); ok {
+				expired = append(expired, lease)
+			}
+		case <-m.wake:
+			m.mu.Lock()
+			expired, m.pending = m.pending, nil
+			m.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+		if m.expired == nil {
+			continue
+		}
+		for _, lease := range expired {
+			select {
+			case m.expired <- lease:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// handle handles expiry e, and returns the expired lease if any.
+func (m *LeaseManager) handle(e leaseExpiry) (Lease, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.leases[e.name]
+	if !ok || l.Token != e.token || time.Now().Before(l.Expiry) {
+		return Lease{}, false // revoked, regranted or renewed
+	}
+	delete(m.leases, e.name)
+	return *l, true
+}
@@ -0,0 +1,95 @@
+package goproc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLeaseManager(t *testing.T) {
+	Convey("With lease manager setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testTTL        = 100 * time.Millisecond
+		)
+		var (
+			expired = make(chan Lease, 10)
+			m       = NewLeaseManager(context.Background(), testResolution, expired)
+		)
+		defer m.Close()
+
+		Convey("Test grant and expire", func() {
+			a, err := m.Grant("a", "worker-1", testTTL)
+			So(err, ShouldBeNil)
+			fmt.Println(a)
+			_, err = m.Grant("a", "worker-2", testTTL)
+			So(err, ShouldEqual, ErrLeaseHeld)
+			b, err := m.Grant("b", "worker-2", 2*testTTL)
+			So(err, ShouldBeNil)
+			So(b.Token, ShouldBeGreaterThan, a.Token)
+			So(m.Active(), ShouldResemble, []Lease{a, b})
+
+			So(<-expired, ShouldResemble, a)
+			So(time.Now(), ShouldHappenOnOrAfter, a.Expiry)
+			_, ok := m.Get("a")
+			So(ok, ShouldBeFalse)
+			So(m.Active(), ShouldResemble, []Lease{b})
+			So(<-expired, ShouldResemble, b)
+			So(m.Active(), ShouldBeEmpty)
+
+			a2, err := m.Grant("a", "worker-2", testTTL)
+			So(err, ShouldBeNil)
+			So(a2.Token, ShouldBeGreaterThan, b.Token)
+			_, err = m.Renew("a", a.Token, testTTL) // fenced
+			So(err, ShouldEqual, ErrLeaseNotFound)
+		})
+		Convey("Test renew", func() {
+			a, err := m.Grant("a", "worker-1", testTTL)
+			So(err, ShouldBeNil)
+			for i := 0; i < 5; i++ {
+				time.Sleep(testTTL / 2)
+				a, err = m.Renew("a", a.Token, testTTL)
+				So(err, ShouldBeNil)
+			}
+			So(expired, ShouldBeEmpty)
+			got, ok := m.Get("a")
+			So(ok, ShouldBeTrue)
+			So(got, ShouldResemble, a)
+
+			a, err = m.Renew("a", a.Token, testTTL/2) // shortened
+			So(err, ShouldBeNil)
+			So(<-expired, ShouldResemble, a)
+			_, err = m.Renew("a", a.Token, testTTL)
+			So(err, ShouldEqual, ErrLeaseNotFound)
+		})
+		Convey("Test revoke", func() {
+			a, err := m.Grant("a", "worker-1", testTTL)
+			So(err, ShouldBeNil)
+			So(m.Revoke("a", a.Token+1), ShouldEqual, ErrLeaseNotFound)
+			So(m.Revoke("a", a.Token), ShouldBeNil)
+			So(m.Revoke("a", a.Token), ShouldEqual, ErrLeaseNotFound)
+			_, ok := m.Get("a")
+			So(ok, ShouldBeFalse)
+			time.Sleep(testTTL + 2*testResolution)
+			So(expired, ShouldBeEmpty)
+		})
+		Convey("Test scheduled expiries after churn", func() {
+			for i := 0; i < 100; i++ {
+				name := fmt.Sprint(i % 10)
+				l, err := m.Grant(name, "worker-1", 10*testTTL)
+				So(err, ShouldBeNil)
+				if i < 90 || i%3 != 0 { // keeps some of the last round
+					So(m.Revoke(name, l.Token), ShouldBeNil)
+				}
+			}
+			So(m.tc.Len(), ShouldEqual, len(m.Active())) // no stale expiries
+			for _, l := range m.Active() {
+				So(m.Revoke(l.Name, l.Token), ShouldBeNil)
+			}
+			So(m.tc.Len(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,254 @@
+// Package metrics publishes goproc Controller and TimeoutChan statistics in Prometheus text
+// exposition format through an http.Handler, and as expvar variables, without depending on the
+// Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leventeliu/goproc"
+)
+
+// ContentType is the content type of Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry is a set of named Controllers and TimeoutChans whose statistics are published. Registry
+// implements http.Handler, which serves the metrics in Prometheus text exposition format.
+type Registry struct {
+	namespace   string
+	mu          *sync.RWMutex
+	controllers map[string]*goproc.Controller
+	chans       map[string]*goproc.TimeoutChan
+}
+
+// NewRegistry creates a new Registry, metric names are prefixed by namespace, e.g. "goproc".
+func NewRegistry(namespace string) *Registry {
+	return &Registry{
+		namespace:   namespace,
+		mu:          &sync.RWMutex{},
+		controllers: make(map[string]*goproc.Controller),
+		chans:       make(map[string]*goproc.TimeoutChan),
+	}
+}
+
+// RegisterController registers c with name, which replaces any Controller registered with the
+// same name.
+func (r *Registry) RegisterController(name string, c *goproc.Controller) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.controllers[name] = c
+}
+
+// UnregisterController unregisters the Controller registered with name.
+func (r *Registry) UnregisterController(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.controllers, name)
+}
+
+// RegisterTimeoutChan registers tc with name, which replaces any TimeoutChan registered with the
+// same name.
+func (r *Registry) RegisterTimeoutChan(name string, tc *goproc.TimeoutChan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chans[name] = tc
+}
+
+// UnregisterTimeoutChan unregisters the TimeoutChan registered with name.
+func (r *Registry) UnregisterTimeoutChan(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.chans, name)
+}
+
+// Snapshot contains statistics of the registered Controllers and TimeoutChans by name.
+type Snapshot struct {
+	Controllers  map[string]goproc.ControllerStats  `json:"controllers"`
+	TimeoutChans map[string]goproc.TimeoutChanStats `json:"timeout_chans"`
+}
+
+// Snapshot returns current statistics of the registered Controllers and TimeoutChans.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := Snapshot{
+		Controllers:  make(map[string]goproc.ControllerStats, len(r.controllers)),
+		TimeoutChans: make(map[string]goproc.TimeoutChanStats, len(r.chans)),
+	}
+	for name, c := range r.controllers {
+		s.Controllers[name] = c.Stats()
+	}
+	for name, tc := range r.chans {
+		s.TimeoutChans[name] = tc.Stats()
+	}
+	return s
+}
+
+// Publish publishes the registry snapshot as an expvar variable with name, which is served at
+// /debug/vars by expvar. Like expvar.Publish, it panics if name is already in use.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return r.Snapshot()
+	}))
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := r.WriteText(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteText writes metrics in Prometheus text exposition format to w.
+func (r *Registry) WriteText(w io.Writer) error {
+	var (
+		s           = r.Snapshot()
+		bw          = bufio.NewWriter(w)
+		controllers = make([]string, 0, len(s.Controllers))
+		chans       = make([]string, 0, len(s.TimeoutChans))
+	)
+	for name := range s.Controllers {
+		controllers = append(controllers, name)
+	}
+	for name := range s.TimeoutChans {
+		chans = append(chans, name)
+	}
+	sort.Strings(controllers)
+	sort.Strings(chans)
+	for _, m := range controllerMetrics {
+		r.header(bw, "controller_"+m.name, m.help, m.kind)
+		for _, name := range controllers {
+			r.sample(bw, "controller_"+m.name, "controller", name, "", float64(m.value(s.Controllers[name])))
+		}
+	}
+	for _, m := range timeoutChanMetrics {
+		r.header(bw, "timeout_chan_"+m.name, m.help, m.kind)
+		for _, name := range chans {
+			r.sample(bw, "timeout_chan_"+m.name, "timeout_chan", name, "", float64(m.value(s.TimeoutChans[name])))
+		}
+	}
+	for _, m := range timeoutChanHistograms {
+		r.header(bw, "timeout_chan_"+m.name, m.help, "histogram")
+		for _, name := range chans {
+			r.histogram(bw, "timeout_chan_"+m.name, "timeout_chan", name, m.value(s.TimeoutChans[name]))
+		}
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) metricName(name string) string {
+	if r.namespace == "" {
+		return name
+	}
+	return r.namespace + "_" + name
+}
+
+func (r *Registry) header(w *bufio.Writer, name, help, kind string) {
+	name = r.metricName(name)
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func (r *Registry) sample(w *bufio.Writer, name, label, value, extra string, v float64) {
+	fmt.Fprintf(w, "%s{%s=\"%s\"%s} %s\n", r.metricName(name), label, escape(value), extra, formatFloat(v))
+}
+
+func (r *Registry) histogram(w *bufio.Writer, name, label, value string, h goproc.HistogramSnapshot) {
+	var cum int
+	for i, bound := range h.Bounds {
+		cum += h.Counts[i]
+		r.sample(w, name+"_bucket", label, value, fmt.Sprintf(",le=\"%s\"", formatFloat(bound.Seconds())), float64(cum))
+	}
+	r.sample(w, name+"_bucket", label, value, ",le=\"+Inf\"", float64(h.Count))
+	r.sample(w, name+"_sum", label, value, "", h.Sum.Seconds())
+	r.sample(w, name+"_count", label, value, "", float64(h.Count))
+}
+
+type controllerMetric struct {
+	name, help, kind string
+	value            func(s goproc.ControllerStats) int64
+}
+
+var controllerMetrics = []controllerMetric{
+	{"goroutines_started_total", "Goroutines started by the controller.", "counter",
+		func(s goproc.ControllerStats) int64 { return s.Started }},
+	{"goroutines_running", "Goroutines currently running under the controller.", "gauge",
+		func(s goproc.ControllerStats) int64 { return s.Running }},
+	{"goroutines_recovered_total", "Panics recovered in goroutines of the controller.", "counter",
+		func(s goproc.ControllerStats) int64 { return s.Recovered }},
+	{"goroutines_skipped_total", "Rate limited goroutines skipped as the controller is cancelled.", "counter",
+		func(s goproc.ControllerStats) int64 { return s.Skipped }},
+}
+
+type timeoutChanMetric struct {
+	name, help, kind string
+	value            func(s goproc.TimeoutChanStats) int
+}
+
+var timeoutChanMetrics = []timeoutChanMetric{
+	{"pushed_total", "Deadliners pushed into the timeout chan.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Pushed }},
+	{"popped_total", "Deadliners popped from the timeout chan.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Popped }},
+	{"cleared_total", "Deadliners cleared from the timeout chan.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Cleared }},
+	{"recovered_total", "Deadliners recovered from the journal.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Recovered }},
+	{"acked_total", "Deadliners acknowledged.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Acked }},
+	{"redelivered_total", "Deadliners redelivered after visibility timeout.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Redelivered }},
+	{"dead_lettered_total", "Deadliners dead-lettered after max attempts.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.DeadLettered }},
+	{"dropped_total", "Deadliners dropped by overflow policy.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Dropped }},
+	{"early_total", "Deadliners delivered early by overflow policy.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Early }},
+	{"recurred_total", "Deliveries of recurring Deadliners' recurrences.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Recurred }},
+	{"clock_jumps_total", "Wall clock jumps detected.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.ClockJumps }},
+	{"replaced_total", "Keyed Deadliners replaced by deduplication.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Replaced }},
+	{"reschedules_total", "Changes of the earliest deadline by pushes.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Reschedules }},
+	{"wakeups_total", "Timer wakeups of the pop process.", "counter",
+		func(s goproc.TimeoutChanStats) int { return s.Wakeups }},
+	{"in_flight", "Deadliners delivered but not acknowledged.", "gauge",
+		func(s goproc.TimeoutChanStats) int { return s.InFlight }},
+	{"queue_length", "Deadliners currently buffered in the timeout chan.", "gauge",
+		func(s goproc.TimeoutChanStats) int { return s.Len }},
+	{"queue_high_water", "Max number of Deadliners ever buffered in the timeout chan.", "gauge",
+		func(s goproc.TimeoutChanStats) int { return s.HighWater }},
+}
+
+type timeoutChanHistogram struct {
+	name, help string
+	value      func(s goproc.TimeoutChanStats) goproc.HistogramSnapshot
+}
+
+var timeoutChanHistograms = []timeoutChanHistogram{
+	{"lateness_seconds", "Time of delivery minus deadline, 0 for deliveries ahead of deadline.",
+		func(s goproc.TimeoutChanStats) goproc.HistogramSnapshot { return s.Lateness }},
+	{"blocking_seconds", "Time blocked on delivery waiting for consumers.",
+		func(s goproc.TimeoutChanStats) goproc.HistogramSnapshot { return s.Blocking }},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leventeliu/goproc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testDeadliner struct {
+	time.Time
+}
+
+func (t testDeadliner) Deadline() time.Time {
+	return t.Time
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("With registry setup", t, func(c C) {
+		const testRounds = 10
+		var (
+			registry = NewRegistry("goproc")
+			ctrl     = goproc.NewController(context.Background(), t.Name())
+			tc       = goproc.NewTimeoutChan(context.Background(), 10*time.Millisecond, 0)
+			idle     = goproc.NewTimeoutChan(context.Background(), 10*time.Millisecond, 0)
+		)
+		defer ctrl.Shutdown()
+		defer tc.Shutdown()
+		defer idle.Shutdown()
+		registry.RegisterController("worker", ctrl)
+		registry.RegisterTimeoutChan("delay", tc)
+		registry.RegisterTimeoutChan(`idle "chan"`, idle)
+
+		ctrl.Go(func(ctx context.Context) { <-ctx.Done() })
+		now := time.Now()
+		for i := 0; i < testRounds; i++ {
+			So(tc.Push(testDeadliner{Time: now.Add(time.Duration(i) * time.Millisecond)}), ShouldBeNil)
+		}
+		for i := 0; i < testRounds; i++ {
+			<-tc.Out
+		}
+		for tc.Stats().Lateness.Count < testRounds { // recorded after sending
+			time.Sleep(time.Millisecond)
+		}
+		So(tc.Push(testDeadliner{Time: now.Add(time.Hour)}), ShouldBeNil)
+
+		Convey("Test prometheus handler", func() {
+			server := httptest.NewServer(registry)
+			defer server.Close()
+			resp, err := http.Get(server.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(resp.Header.Get("Content-Type"), ShouldEqual, ContentType)
+			body, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			text := string(body)
+			So(text, ShouldContainSubstring, "# TYPE goproc_controller_goroutines_running gauge\n")
+			So(text, ShouldContainSubstring, `goproc_controller_goroutines_started_total{controller="worker"} 1`+"\n")
+			So(text, ShouldContainSubstring, `goproc_controller_goroutines_running{controller="worker"} 1`+"\n")
+			So(text, ShouldContainSubstring, "# TYPE goproc_timeout_chan_pushed_total counter\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_pushed_total{timeout_chan="delay"} 11`+"\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_popped_total{timeout_chan="delay"} 10`+"\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_queue_length{timeout_chan="delay"} 1`+"\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_pushed_total{timeout_chan="idle \"chan\""} 0`+"\n")
+			So(text, ShouldContainSubstring, "# TYPE goproc_timeout_chan_lateness_seconds histogram\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_lateness_seconds_bucket{timeout_chan="delay",le="10"} 10`+"\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_lateness_seconds_bucket{timeout_chan="delay",le="+Inf"} 10`+"\n")
+			So(text, ShouldContainSubstring, `goproc_timeout_chan_lateness_seconds_count{timeout_chan="delay"} 10`+"\n")
+
+			registry.UnregisterTimeoutChan("delay")
+			registry.UnregisterController("worker")
+			resp, err = http.Get(server.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			body, err = ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			So(string(body), ShouldNotContainSubstring, `"delay"`)
+			So(string(body), ShouldNotContainSubstring, `"worker"`)
+		})
+		Convey("Test expvar", func() {
+			registry.Publish(t.Name())
+			var s Snapshot
+			So(json.Unmarshal([]byte(expvar.Get(t.Name()).String()), &s), ShouldBeNil)
+			So(s.Controllers["worker"].Running, ShouldEqual, 1)
+			So(s.TimeoutChans["delay"].Pushed, ShouldEqual, 11)
+			So(s.TimeoutChans["delay"].Lateness.Count, ShouldEqual, 10)
+			So(s.TimeoutChans[`idle "chan"`].Len, ShouldEqual, 0)
+		})
+	})
+}
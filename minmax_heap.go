@@ -0,0 +1,233 @@
+package goproc
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// MinMaxHeap is a double-ended priority queue implemented as a min-max heap, both the lowest and
+// the highest priority elements can be peeked in O(1) time and popped in O(log n) time.
+type MinMaxHeap struct {
+	heap []Prioritier
+}
+
+// NewMinMaxHeap creates a new MinMaxHeap.
+func NewMinMaxHeap(size int) *MinMaxHeap {
+	return &MinMaxHeap{
+		heap: make([]Prioritier, 0, size),
+	}
+}
+
+// Len returns the number of elements in the heap.
+func (h *MinMaxHeap) Len() int { return len(h.heap) }
+
+// Insert pushes x onto the heap.
+func (h *MinMaxHeap) Insert(x Prioritier) {
+	h.heap = append(h.heap, x)
+	h.up(len(h.heap) - 1)
+}
+
+// PeekMin returns the lowest priority element of the heap. User should ensure the heap is not
+// empty before calling PeekMin.
+func (h *MinMaxHeap) PeekMin() Prioritier {
+	return h.heap[0]
+}
+
+// PeekMax returns the highest priority element of the heap. User should ensure the heap is not
+// empty before calling PeekMax.
+func (h *MinMaxHeap) PeekMax() Prioritier {
+	return h.heap[h.maxIndex()]
+}
+
+// PopMin removes and returns the lowest priority element of the heap. User should ensure the heap
+// is not empty before calling PopMin.
+func (h *MinMaxHeap) PopMin() Prioritier {
+	return h.remove(0)
+}
+
+// PopMax removes and returns the highest priority element of the heap. User should ensure the heap
+// is not empty before calling PopMax.
+func (h *MinMaxHeap) PopMax() Prioritier {
+	return h.remove(h.maxIndex())
+}
+
+// Clear clears the heap.
+func (h *MinMaxHeap) Clear() int {
+	l := len(h.heap)
+	for i := range h.heap {
+		h.heap[i] = nil // release references
+	}
+	h.heap = h.heap[:0]
+	return l
+}
+
+func (h *MinMaxHeap) less(i, j int) bool {
+	return h.heap[i].Priority() < h.heap[j].Priority()
+}
+
+func (h *MinMaxHeap) swap(i, j int) {
+	h.heap[i], h.heap[j] = h.heap[j], h.heap[i]
+}
+
+func (h *MinMaxHeap) maxIndex() int {
+	switch len(h.heap) {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	default:
+		if h.less(1, 2) {
+			return 2
+		}
+		return 1
+	}
+}
+
+func (h *MinMaxHeap) remove(i int) Prioritier {
+	l := len(h.heap) - 1
+	item := h.heap[i]
+	h.heap[i] = h.heap[l]
+	h.heap[l] = nil
+	h.heap = h.heap[:l]
+	if i < l {
+		h.down(i)
+	}
+	return item
+}
+
+// isMinLevel tells whether the node at index i is on a min level, i.e. an even depth.
+func isMinLevel(i int) bool {
+	return (bits.Len(uint(i+1))-1)%2 == 0
+}
+
+func (h *MinMaxHeap) up(i int) {
+	if i == 0 {
+		return
+	}
+	p := (i - 1) / 2
+	if isMinLevel(i) {
+		if h.less(p, i) {
+			h.swap(i, p)
+			h.upLevel(p, true)
+		} else {
+			h.upLevel(i, false)
+		}
+	} else {
+		if h.less(i, p) {
+			h.swap(i, p)
+			h.upLevel(p, false)
+		} else {
+			h.upLevel(i, true)
+		}
+	}
+}
+
+// upLevel moves the node at index i up through its grandparents, which are on the same kind of
+// level. With max set, the node is treated as on a max level.
+func (h *MinMaxHeap) upLevel(i int, max bool) {
+	for i > 2 {
+		gp := ((i-1)/2 - 1) / 2
+		if max && !h.less(gp, i) || !max && !h.less(i, gp) {
+			return
+		}
+		h.swap(i, gp)
+		i = gp
+	}
+}
+
+func (h *MinMaxHeap) down(i int) {
+	max := !isMinLevel(i)
+	// better tells whether node a should be placed above node b on the current kind of level
+	better := func(a, b int) bool {
+		if max {
+			return h.less(b, a)
+		}
+		return h.less(a, b)
+	}
+	for {
+		// Find the best one among children and grandchildren
+		m := -1
+		first := 2*i + 1
+		for _, j := range []int{first, first + 1, 2*first + 1, 2*first + 2, 2*first + 3, 2*first + 4} {
+			if j >= len(h.heap) {
+				break
+			}
+			if m < 0 || better(j, m) {
+				m = j
+			}
+		}
+		if m < 0 || !better(m, i) {
+			return
+		}
+		h.swap(m, i)
+		if m <= first+1 {
+			return // m is a child, which is the last step
+		}
+		if p := (m - 1) / 2; better(p, m) {
+			h.swap(m, p)
+		}
+		i = m
+	}
+}
+
+// TopK is a bounded collector which keeps the K highest priority elements offered to it.
+type TopK struct {
+	k    int
+	heap *MinMaxHeap
+}
+
+// NewTopK creates a new TopK collector with capacity k.
+func NewTopK(k int) *TopK {
+	if k <= 0 {
+		panic("goproc: non-positive TopK capacity")
+	}
+	return &TopK{
+		k:    k,
+		heap: NewMinMaxHeap(k),
+	}
+}
+
+// Offer offers x to the collector. If the collector is at capacity, the lowest priority element
+// among the collected ones and x is evicted and returned - which can be x itself. Otherwise nil
+// is returned.
+func (t *TopK) Offer(x Prioritier) Prioritier {
+	if t.heap.Len() < t.k {
+		t.heap.Insert(x)
+		return nil
+	}
+	if x.Priority() <= t.heap.PeekMin().Priority() {
+		return x
+	}
+	evicted := t.heap.PopMin()
+	t.heap.Insert(x)
+	return evicted
+}
+
+// Len returns the number of collected elements.
+func (t *TopK) Len() int { return t.heap.Len() }
+
+// Cap returns the capacity of the collector.
+func (t *TopK) Cap() int { return t.k }
+
+// Min returns the lowest priority element among the collected ones, which is the next one to be
+// evicted. User should ensure the collector is not empty before calling Min.
+func (t *TopK) Min() Prioritier { return t.heap.PeekMin() }
+
+// Max returns the highest priority element among the collected ones. User should ensure the
+// collector is not empty before calling Max.
+func (t *TopK) Max() Prioritier { return t.heap.PeekMax() }
+
+// Items returns the collected elements in descending priority order.
+func (t *TopK) Items() []Prioritier {
+	items := make([]Prioritier, len(t.heap.heap))
+	copy(items, t.heap.heap)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].Priority() < items[i].Priority()
+	})
+	return items
+}
+
+// Clear clears the collector.
+func (t *TopK) Clear() int {
+	return t.heap.Clear()
+}
@@ -0,0 +1,92 @@
+package goproc
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testPrioritier int64
+
+func (p testPrioritier) Priority() int64 {
+	return int64(p)
+}
+
+func TestMinMaxHeap(t *testing.T) {
+	Convey("With min-max heap setup", t, func(c C) {
+		const testRounds = 1000
+		var (
+			h      = NewMinMaxHeap(0)
+			inList = make([]int64, testRounds)
+		)
+		for i := range inList {
+			inList[i] = rand.Int63n(testRounds / 2) // with duplicates
+			h.Insert(testPrioritier(inList[i]))
+		}
+		sort.Slice(inList, func(i, j int) bool { return inList[i] < inList[j] })
+		So(h.Len(), ShouldEqual, testRounds)
+
+		Convey("Test pop min", func() {
+			for _, expected := range inList {
+				So(h.PeekMin().Priority(), ShouldEqual, expected)
+				So(h.PopMin().Priority(), ShouldEqual, expected)
+			}
+			So(h.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Test pop max", func() {
+			for i := len(inList) - 1; i >= 0; i-- {
+				So(h.PeekMax().Priority(), ShouldEqual, inList[i])
+				So(h.PopMax().Priority(), ShouldEqual, inList[i])
+			}
+			So(h.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Test pop both ends", func() {
+			for lo, hi := 0, len(inList)-1; lo <= hi; lo, hi = lo+1, hi-1 {
+				So(h.PopMin().Priority(), ShouldEqual, inList[lo])
+				if lo < hi {
+					So(h.PopMax().Priority(), ShouldEqual, inList[hi])
+				}
+			}
+			So(h.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Test clear", func() {
+			So(h.Clear(), ShouldEqual, testRounds)
+			So(h.Len(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestTopK(t *testing.T) {
+	Convey("With top-k collector setup", t, func(c C) {
+		const (
+			testK      = 10
+			testRounds = 1000
+		)
+		var (
+			topK    = NewTopK(testK)
+			inList  = make([]int64, testRounds)
+			evicted int
+		)
+		for i := range inList {
+			inList[i] = rand.Int63()
+			if e := topK.Offer(testPrioritier(inList[i])); e != nil {
+				evicted++
+				So(e.Priority(), ShouldBeLessThanOrEqualTo, topK.Min().Priority())
+			}
+		}
+		sort.Slice(inList, func(i, j int) bool { return inList[j] < inList[i] })
+
+		So(topK.Len(), ShouldEqual, testK)
+		So(evicted, ShouldEqual, testRounds-testK)
+		So(topK.Max().Priority(), ShouldEqual, inList[0])
+		So(topK.Min().Priority(), ShouldEqual, inList[testK-1])
+		for i, item := range topK.Items() {
+			So(item.Priority(), ShouldEqual, inList[i])
+		}
+	})
+}
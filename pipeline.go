@@ -0,0 +1,294 @@
+package goproc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// StageFunc defines the function type of pipeline stages, which transforms an item to the one
+// sent to the next stage.
+type StageFunc func(ctx context.Context, item interface{}) (interface{}, error)
+
+// PipelineMode defines whether a Pipeline keeps the order of items.
+type PipelineMode int
+
+const (
+	// PipelineUnordered sends items to the next stage as soon as they are processed, so items
+	// may be reordered by stages with parallelism greater than 1.
+	PipelineUnordered PipelineMode = iota
+	// PipelineOrdered sends items to the next stage in the order they are received, at the cost of
+	// holding items processed early - a stage works on at most parallelism plus buffer size items
+	// ahead of the earliest one not processed.
+	PipelineOrdered
+)
+
+// StageErrorPolicy defines the behavior of a stage when its StageFunc returns an error.
+type StageErrorPolicy int
+
+const (
+	// StageErrorStop cancels the whole pipeline, and Pipeline.Wait returns the error. This is the
+	// default policy.
+	StageErrorStop StageErrorPolicy = iota
+	// StageErrorSkip drops the item and keeps going.
+	StageErrorSkip
+)
+
+// StageOption configures a pipeline stage.
+type StageOption func(s *stage)
+
+// WithParallelism sets the number of goroutines running a stage, which is 1 by default.
+func WithParallelism(n int) StageOption {
+	return func(s *stage) {
+		s.parallelism = n
+	}
+}
+
+// WithBufferSize sets the buffer size of the output channel of a stage, which is 0 by default.
+func WithBufferSize(n int) StageOption {
+	return func(s *stage) {
+		s.buffer = n
+	}
+}
+
+// WithErrorPolicy sets the error policy of a stage. The optional onSkip is called with each item
+// dropped by StageErrorSkip policy and the error.
+func WithErrorPolicy(policy StageErrorPolicy, onSkip func(item interface{}, err error)) StageOption {
+	return func(s *stage) {
+		s.policy = policy
+		s.onSkip = onSkip
+	}
+}
+
+type stage struct {
+	fn          StageFunc
+	parallelism int
+	buffer      int
+	policy      StageErrorPolicy
+	onSkip      func(item interface{}, err error)
+}
+
+// stageResult is the result of an item in ordered mode.
+type stageResult struct {
+	item  interface{}
+	value interface{}
+	err   error
+}
+
+// stageJob is an item dispatched to the workers of a stage in ordered mode.
+type stageJob struct {
+	item   interface{}
+	result chan stageResult
+}
+
+// Pipeline connects stages by channels, each stage has its own parallelism, buffer size and error
+// policy. All stage goroutines are owned by the Pipeline Controller, so the first error - or panic -
+// of any stage cancels the whole pipeline.
+type Pipeline struct {
+	ctrl   *Controller
+	mode   PipelineMode
+	stages []*stage
+
+	mu      *sync.Mutex
+	started bool
+	err     error
+}
+
+// NewPipeline creates a new Pipeline without stages.
+func NewPipeline(ctx context.Context, mode PipelineMode) *Pipeline {
+	return &Pipeline{
+		ctrl: NewController(ctx, "Pipeline"),
+		mode: mode,
+		mu:   &sync.Mutex{},
+	}
+}
+
+// Stage appends a stage running fn to p, and returns p for chaining. It panics if p is started.
+func (p *Pipeline) Stage(fn StageFunc, opts ...StageOption) *Pipeline {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		panic("goproc: pipeline is started")
+	}
+	s := &stage{fn: fn, parallelism: 1}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.parallelism < 1 {
+		panic("goproc: parallelism less than 1")
+	}
+	p.stages = append(p.stages, s)
+	return p
+}
+
+// Run starts p with items from source, and returns the output channel of the last stage, which is
+// closed when source is closed and all items are processed, or p is cancelled. The output must be
+// drained, or p blocks. Run panics if p is already started or cancelled.
+func (p *Pipeline) Run(source <-chan interface{}) <-chan interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		panic("goproc: pipeline is started")
+	}
+	p.started = true
+	in := source
+	for _, s := range p.stages {
+		if p.mode == PipelineOrdered {
+			in = p.runOrdered(s, in)
+		} else {
+			in = p.runUnordered(s, in)
+		}
+	}
+	return in
+}
+
+// Wait waits for all stages to return, and returns the first error of p: an error returned by a
+// stage with StageErrorStop policy, a panic of a stage, or the context error if p is cancelled.
+func (p *Pipeline) Wait() error {
+	p.ctrl.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Cancel cancels p and waits for all stages to return.
+func (p *Pipeline) Cancel() {
+	p.fail(context.Canceled)
+	p.ctrl.Wait()
+}
+
+// fail records err as the error of p if it's the first, and cancels p.
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.ctrl.cancel()
+}
+
+// goStage starts g on a goroutine owned by p, a panic of g fails p.
+func (p *Pipeline) goStage(g Goroutine) {
+	p.ctrl.GoWithRecover(g, func(r interface{}) {
+		p.fail(fmt.Errorf("goproc: pipeline stage panics: %v", r))
+	})
+}
+
+// emit handles the result of item by the error policy of s, and sends value to out. It returns
+// false if p is cancelled.
+func (p *Pipeline) emit(ctx context.Context, s *stage, out chan<- interface{}, item, value interface{}, err error) bool {
+	if err != nil {
+		if s.policy == StageErrorStop {
+			p.fail(err)
+			return false
+		}
+		if s.onSkip != nil {
+			s.onSkip(item, err)
+		}
+		return true
+	}
+	select {
+	case out <- value:
+		return true
+	case <-ctx.Done():
+		p.fail(ctx.Err())
+		return false
+	}
+}
+
+// receive receives an item from in, it returns false if in is closed or p is cancelled.
+func (p *Pipeline) receive(ctx context.Context, in <-chan interface{}) (interface{}, bool) {
+	select {
+	case item, ok := <-in:
+		return item, ok
+	case <-ctx.Done():
+		p.fail(ctx.Err())
+		return nil, false
+	}
+}
+
+func (p *Pipeline) runUnordered(s *stage, in <-chan interface{}) <-chan interface{} {
+	var (
+		out = make(chan interface{}, s.buffer)
+		wg  = &sync.WaitGroup{}
+	)
+	wg.Add(s.parallelism)
+	for i := 0; i < s.parallelism; i++ {
+		p.goStage(func(ctx context.Context) {
+			defer wg.Done()
+			for {
+				item, ok := p.receive(ctx, in)
+				if !ok {
+					return
+				}
+				value, err := s.fn(ctx, item)
+				if !p.emit(ctx, s, out, item, value, err) {
+					return
+				}
+			}
+		})
+	}
+	p.ctrl.Go(func(ctx context.Context) {
+		wg.Wait()
+		close(out)
+	})
+	return out
+}
+
+func (p *Pipeline) runOrdered(s *stage, in <-chan interface{}) <-chan interface{} {
+	var (
+		out     = make(chan interface{}, s.buffer)
+		jobs    = make(chan stageJob, s.parallelism)
+		results = make(chan chan stageResult, s.parallelism+s.buffer) // in order of items
+	)
+	// Dispatcher
+	p.goStage(func(ctx context.Context) {
+		defer close(results)
+		defer close(jobs)
+		for {
+			item, ok := p.receive(ctx, in)
+			if !ok {
+				return
+			}
+			job := stageJob{item: item, result: make(chan stageResult, 1)}
+			select {
+			case results <- job.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	// Workers
+	for i := 0; i < s.parallelism; i++ {
+		p.goStage(func(ctx context.Context) {
+			for job := range jobs {
+				value, err := s.fn(ctx, job.item)
+				if err != nil && s.policy == StageErrorStop {
+					p.fail(err) // without waiting for the collector to reach the item
+				}
+				job.result <- stageResult{item: job.item, value: value, err: err}
+			}
+		})
+	}
+	// Collector
+	p.goStage(func(ctx context.Context) {
+		defer close(out)
+		for result := range results {
+			select {
+			case r := <-result:
+				if !p.emit(ctx, s, out, r.item, r.value, r.err) {
+					return
+				}
+			case <-ctx.Done():
+				p.fail(ctx.Err())
+				return
+			}
+		}
+	})
+	return out
+}
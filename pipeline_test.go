@@ -0,0 +1,129 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPipeline(t *testing.T) {
+	Convey("With pipeline setup", t, func(c C) {
+		const testItems = 100
+		var (
+			errTest = errors.New("test")
+			source  = func(n int) <-chan interface{} {
+				ch := make(chan interface{})
+				go func() {
+					defer close(ch)
+					for i := 0; i < n; i++ {
+						ch <- i
+					}
+				}()
+				return ch
+			}
+			jitter = func(ctx context.Context, item interface{}) (interface{}, error) {
+				time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+				return item, nil
+			}
+			double = func(ctx context.Context, item interface{}) (interface{}, error) {
+				return item.(int) * 2, nil
+			}
+			collect = func(out <-chan interface{}) (items []int) {
+				for item := range out {
+					items = append(items, item.(int))
+				}
+				return
+			}
+			expected []int
+		)
+		for i := 0; i < testItems; i++ {
+			expected = append(expected, 2*i)
+		}
+
+		Convey("Test ordered mode", func() {
+			p := NewPipeline(context.Background(), PipelineOrdered).
+				Stage(jitter, WithParallelism(8), WithBufferSize(4)).
+				Stage(double, WithParallelism(2))
+			So(collect(p.Run(source(testItems))), ShouldResemble, expected)
+			So(p.Wait(), ShouldBeNil)
+		})
+		Convey("Test unordered mode", func() {
+			p := NewPipeline(context.Background(), PipelineUnordered).
+				Stage(jitter, WithParallelism(8)).
+				Stage(double, WithBufferSize(4))
+			items := collect(p.Run(source(testItems)))
+			So(p.Wait(), ShouldBeNil)
+			sort.Ints(items)
+			So(items, ShouldResemble, expected)
+		})
+		Convey("Test error skip", func() {
+			var (
+				mu      sync.Mutex
+				skipped []interface{}
+			)
+			for _, mode := range []PipelineMode{PipelineUnordered, PipelineOrdered} {
+				skipped = nil
+				p := NewPipeline(context.Background(), mode).
+					Stage(func(ctx context.Context, item interface{}) (interface{}, error) {
+						if item.(int)%2 == 1 {
+							return nil, errTest
+						}
+						return item, nil
+					}, WithParallelism(4), WithErrorPolicy(StageErrorSkip, func(item interface{}, err error) {
+						mu.Lock()
+						defer mu.Unlock()
+						skipped = append(skipped, item)
+					}))
+				items := collect(p.Run(source(10)))
+				So(p.Wait(), ShouldBeNil)
+				sort.Ints(items)
+				So(items, ShouldResemble, []int{0, 2, 4, 6, 8})
+				So(len(skipped), ShouldEqual, 5)
+			}
+		})
+		Convey("Test first error cancels all stages", func() {
+			for _, mode := range []PipelineMode{PipelineUnordered, PipelineOrdered} {
+				var (
+					cancelled = make(chan struct{})
+					p         = NewPipeline(context.Background(), mode)
+				)
+				p.Stage(func(ctx context.Context, item interface{}) (interface{}, error) {
+					switch item.(int) {
+					case 0:
+						<-ctx.Done() // blocks until cancelled
+						close(cancelled)
+					case 2: // within the reorder window of item 0
+						return nil, errTest
+					}
+					return item, nil
+				}, WithParallelism(4)).
+					Stage(double)
+				out := p.Run(source(testItems))
+				So(len(collect(out)), ShouldBeLessThan, testItems)
+				So(p.Wait(), ShouldEqual, errTest)
+				<-cancelled
+			}
+		})
+		Convey("Test panic and cancel", func() {
+			p := NewPipeline(context.Background(), PipelineOrdered).
+				Stage(func(ctx context.Context, item interface{}) (interface{}, error) {
+					panic("oops")
+				})
+			collect(p.Run(source(testItems)))
+			So(p.Wait().Error(), ShouldContainSubstring, "oops")
+
+			p = NewPipeline(context.Background(), PipelineUnordered).Stage(double)
+			out := p.Run(source(testItems))
+			<-out
+			p.Cancel()
+			So(p.Wait(), ShouldEqual, context.Canceled)
+			So(func() { p.Stage(double) }, ShouldPanic)
+		})
+	})
+}
@@ -9,6 +9,19 @@ type Prioritier interface {
 	Priority() int64
 }
 
+// Indexer is an optional interface of elements in PriorityQueue, whose SetIndex is called with the
+// index of the element whenever it changes, or -1 when the element leaves the queue. The index can
+// be used with PriorityQueue.Remove and PriorityQueue.Fix.
+type Indexer interface {
+	SetIndex(i int)
+}
+
+func setIndex(x Prioritier, i int) {
+	if ix, ok := x.(Indexer); ok {
+		ix.SetIndex(i)
+	}
+}
+
 // PriorityQueue is heap-implementation of priority queue.
 type PriorityQueue struct {
 	heap []Prioritier
@@ -17,20 +30,37 @@ type PriorityQueue struct {
 
 // NewPriorityQueue creates a new PriorityQueue.
 func NewPriorityQueue(desc bool, size int) *PriorityQueue {
-	var less func(i, j int64) bool
-	if desc {
-		less = ge
-	} else {
-		less = lt
-	}
 	pq := &PriorityQueue{
 		heap: make([]Prioritier, 0, size),
-		less: less,
+		less: lessFunc(desc),
 	}
 	heap.Init(pq) // not really necessary, just FYI
 	return pq
 }
 
+// NewPriorityQueueFrom creates a new PriorityQueue holding a copy of items, which is heapified in
+// O(n) time. Like NewPriorityQueue, desc selects the order of the queue, which items alone can't
+// tell, e.g. a descending queue pops the item of the highest priority first.
+func NewPriorityQueueFrom(desc bool, items []Prioritier) *PriorityQueue {
+	pq := &PriorityQueue{
+		heap: make([]Prioritier, len(items)),
+		less: lessFunc(desc),
+	}
+	copy(pq.heap, items)
+	for i, item := range pq.heap {
+		setIndex(item, i)
+	}
+	heap.Init(pq)
+	return pq
+}
+
+func lessFunc(desc bool) func(i, j int64) bool {
+	if desc {
+		return ge
+	}
+	return lt
+}
+
 func lt(a, b int64) bool {
 	return a < b
 }
@@ -43,7 +73,11 @@ func ge(a, b int64) bool {
 func (q PriorityQueue) Len() int { return len(q.heap) }
 
 // Swap implements Swap method of sort.Interface.
-func (q PriorityQueue) Swap(i, j int) { q.heap[i], q.heap[j] = q.heap[j], q.heap[i] }
+func (q PriorityQueue) Swap(i, j int) {
+	q.heap[i], q.heap[j] = q.heap[j], q.heap[i]
+	setIndex(q.heap[i], i)
+	setIndex(q.heap[j], j)
+}
 
 // Less implements Less method of sort.Interface.
 func (q PriorityQueue) Less(i, j int) bool {
@@ -52,14 +86,18 @@ func (q PriorityQueue) Less(i, j int) bool {
 
 // Push implements Push method of heap.Interface.
 func (q *PriorityQueue) Push(x interface{}) {
-	q.heap = append(q.heap, x.(Prioritier))
+	item := x.(Prioritier)
+	setIndex(item, len(q.heap))
+	q.heap = append(q.heap, item)
 }
 
 // Pop implements Pop method of heap.Interface.
 func (q *PriorityQueue) Pop() interface{} {
 	l := len(q.heap)
 	item := q.heap[l-1]
+	q.heap[l-1] = nil // avoid memory leak
 	q.heap = q.heap[:l-1]
+	setIndex(item, -1)
 	return item
 }
 
@@ -71,7 +109,118 @@ func (q *PriorityQueue) Peek() interface{} {
 // Clear clears priority queue.
 func (q *PriorityQueue) Clear() int {
 	l := q.Len()
+	for i, item := range q.heap {
+		setIndex(item, -1)
+		q.heap[i] = nil
+	}
 	q.heap = q.heap[:0]
 	heap.Init(q)
 	return l
 }
+
+// Remove removes and returns the element at index i from the priority queue in O(log n) time.
+func (q *PriorityQueue) Remove(i int) Prioritier {
+	return heap.Remove(q, i).(Prioritier)
+}
+
+// Fix re-establishes the heap ordering after the priority of the element at index i has changed,
+// in O(log n) time.
+func (q *PriorityQueue) Fix(i int) {
+	heap.Fix(q, i)
+}
+
+// PushAll pushes all items onto the priority queue. When items outnumber the queued elements, the
+// queue is re-heapified in O(n) time instead of pushing items one by one.
+func (q *PriorityQueue) PushAll(items ...Prioritier) {
+	if len(items) < q.Len() {
+		for _, item := range items {
+			heap.Push(q, item)
+		}
+		return
+	}
+	for i, item := range items {
+		setIndex(item, q.Len()+i)
+	}
+	q.heap = append(q.heap, items...)
+	heap.Init(q)
+}
+
+// Merge absorbs all elements of other into q, other will be empty after merging.
+func (q *PriorityQueue) Merge(other *PriorityQueue) {
+	if other == q {
+		return
+	}
+	q.PushAll(other.heap...)
+	// Not other.Clear, which would reset indexes of the moved elements
+	for i := range other.heap {
+		other.heap[i] = nil
+	}
+	other.heap = other.heap[:0]
+}
+
+// Drain removes all elements from the priority queue and returns them in priority order.
+func (q *PriorityQueue) Drain() []Prioritier {
+	items := make([]Prioritier, 0, q.Len())
+	for q.Len() > 0 {
+		items = append(items, heap.Pop(q).(Prioritier))
+	}
+	return items
+}
+
+// Iterate returns an iterator over elements of the priority queue in priority order. Iterating
+// doesn't modify the queue, but the queue should not be modified before the iteration ends.
+func (q *PriorityQueue) Iterate() *PriorityQueueIterator {
+	it := &PriorityQueueIterator{q: q}
+	if q.Len() > 0 {
+		it.pending = append(it.pending, 0)
+	}
+	return it
+}
+
+// PriorityQueueIterator is an ordered non-destructive iterator of PriorityQueue, which costs
+// O(k log k) time to iterate the first k elements.
+type PriorityQueueIterator struct {
+	q       *PriorityQueue
+	pending []int // heap of indices in q, whose elements are candidates of the next one
+}
+
+// Next returns the next element in priority order, or false if the iteration ends.
+func (it *PriorityQueueIterator) Next() (Prioritier, bool) {
+	if len(it.pending) == 0 {
+		return nil, false
+	}
+	i := heap.Pop(it).(int)
+	// Children of the visited element are the only new candidates
+	for _, child := range []int{2*i + 1, 2*i + 2} {
+		if child < it.q.Len() {
+			heap.Push(it, child)
+		}
+	}
+	return it.q.heap[i], true
+}
+
+// Len implements Len method of sort.Interface.
+func (it *PriorityQueueIterator) Len() int { return len(it.pending) }
+
+// Swap implements Swap method of sort.Interface.
+func (it *PriorityQueueIterator) Swap(i, j int) {
+	it.pending[i], it.pending[j] = it.pending[j], it.pending[i]
+}
+
+// Less implements Less method of sort.Interface.
+func (it *PriorityQueueIterator) Less(i, j int) bool {
+	return it.q.Less(it.pending[i], it.pending[j])
+}
+
+// Push implements Push method of heap.Interface.
+func (it *PriorityQueueIterator) Push(x interface{}) {
+	it.pending = append(it.pending, x.(int))
+}
+
+// Pop implements Pop method of heap.Interface.
+func (it *PriorityQueueIterator) Pop() interface{} {
+	l := len(it.pending)
+	i := it.pending[l-1]
+	it.pending = it.pending[:l-1]
+	return i
+}
@@ -0,0 +1,177 @@
+package goproc
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPriorityQueueBulkOperations(t *testing.T) {
+	Convey("With priority queue built from items", t, func(c C) {
+		const testRounds = 1000
+		var (
+			items    = make([]Prioritier, testRounds)
+			expected = make([]int64, 0, 2*testRounds)
+		)
+		for i := range items {
+			items[i] = testPrioritier(rand.Int63n(testRounds))
+			expected = append(expected, items[i].Priority())
+		}
+		pq := NewPriorityQueueFrom(false, items)
+		So(pq.Len(), ShouldEqual, testRounds)
+
+		checkDrain := func() {
+			sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+			drained := pq.Drain()
+			So(pq.Len(), ShouldEqual, 0)
+			So(len(drained), ShouldEqual, len(expected))
+			for i, item := range drained {
+				So(item.Priority(), ShouldEqual, expected[i])
+			}
+		}
+
+		Convey("Test drain", func() {
+			checkDrain()
+		})
+
+		Convey("Test push all", func() {
+			for _, n := range []int{10, 2 * testRounds} { // both incremental and re-heapifying paths
+				more := make([]Prioritier, n)
+				for i := range more {
+					more[i] = testPrioritier(rand.Int63n(testRounds))
+					expected = append(expected, more[i].Priority())
+				}
+				pq.PushAll(more...)
+			}
+			checkDrain()
+		})
+
+		Convey("Test merge", func() {
+			other := NewPriorityQueue(true, 0)
+			for i := 0; i < testRounds; i++ {
+				item := testPrioritier(rand.Int63n(testRounds))
+				other.Insert(item)
+				expected = append(expected, item.Priority())
+			}
+			pq.Merge(other)
+			So(other.Len(), ShouldEqual, 0)
+			checkDrain()
+		})
+	})
+}
+
+func TestPriorityQueueIterator(t *testing.T) {
+	Convey("Test priority queue ordered iteration", t, func(c C) {
+		const testRounds = 1000
+		var (
+			pq       = NewPriorityQueue(true, testRounds)
+			expected = make([]int64, testRounds)
+		)
+		for i := range expected {
+			expected[i] = rand.Int63n(testRounds)
+			pq.Insert(testPrioritier(expected[i]))
+		}
+		sort.Slice(expected, func(i, j int) bool { return expected[j] < expected[i] })
+
+		var iterated []int64
+		for it := pq.Iterate(); ; {
+			item, ok := it.Next()
+			if !ok {
+				break
+			}
+			iterated = append(iterated, item.Priority())
+		}
+		So(iterated, ShouldResemble, expected)
+		So(pq.Len(), ShouldEqual, testRounds)
+		for _, p := range expected {
+			So(pq.Extract().Priority(), ShouldEqual, p)
+		}
+	})
+}
+
+type testIndexedPrioritier struct {
+	priority int64
+	index    int
+}
+
+func (p *testIndexedPrioritier) Priority() int64 {
+	return p.priority
+}
+
+func (p *testIndexedPrioritier) SetIndex(i int) {
+	p.index = i
+}
+
+func TestPriorityQueueRemoveAndFix(t *testing.T) {
+	Convey("With priority queue of indexed items", t, func(c C) {
+		const testRounds = 1000
+		var (
+			pq    = NewPriorityQueue(false, testRounds)
+			items = make([]*testIndexedPrioritier, testRounds)
+		)
+		for i := range items {
+			items[i] = &testIndexedPrioritier{priority: rand.Int63n(testRounds)}
+			if i < testRounds/2 {
+				pq.Insert(items[i])
+			}
+		}
+		bulk := make([]Prioritier, 0, testRounds/2)
+		for _, item := range items[testRounds/2:] {
+			bulk = append(bulk, item)
+		}
+		pq.PushAll(bulk...)
+		for _, item := range items {
+			So(pq.heap[item.index], ShouldEqual, item)
+		}
+
+		var expected []int64
+		for i, item := range items {
+			switch i % 3 {
+			case 0:
+				So(pq.Remove(item.index), ShouldEqual, item)
+				So(item.index, ShouldEqual, -1)
+			case 1:
+				item.priority = rand.Int63n(testRounds)
+				pq.Fix(item.index)
+				expected = append(expected, item.priority)
+			default:
+				expected = append(expected, item.priority)
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+		So(pq.Len(), ShouldEqual, len(expected))
+		for _, p := range expected {
+			So(pq.Extract().Priority(), ShouldEqual, p)
+		}
+	})
+}
+
+func TestPriorityQueueMergeIndexed(t *testing.T) {
+	Convey("Test priority queue merge of indexed items", t, func(c C) {
+		const testRounds = 1000
+		for _, n := range []int{10, 2 * testRounds} { // both incremental and re-heapifying paths
+			var (
+				pq    = NewPriorityQueue(false, testRounds)
+				other = NewPriorityQueue(false, n)
+				items = make([]*testIndexedPrioritier, 0, testRounds+n)
+			)
+			for i := 0; i < testRounds+n; i++ {
+				item := &testIndexedPrioritier{priority: rand.Int63n(testRounds)}
+				if i < testRounds {
+					pq.Insert(item)
+				} else {
+					other.Insert(item)
+				}
+				items = append(items, item)
+			}
+			pq.Merge(other)
+			So(other.Len(), ShouldEqual, 0)
+			So(pq.Len(), ShouldEqual, testRounds+n)
+			for _, item := range items {
+				So(pq.heap[item.index], ShouldEqual, item)
+			}
+		}
+	})
+}
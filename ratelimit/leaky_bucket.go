@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeakyBucket is a leaky bucket limiter, which queues events in a bucket of a capacity and permits
+// them one per interval. Unlike TokenBucket, it smooths bursts out: permitted events are always at
+// least an interval apart, thus Allow only permits an event if the bucket is empty.
+type LeakyBucket struct {
+	interval time.Duration
+	capacity int
+	now      func() time.Time
+
+	mu   *sync.Mutex
+	next time.Time // time of the next permit
+}
+
+// NewLeakyBucket creates a new LeakyBucket which permits an event per interval, and queues at most
+// capacity events to be permitted - Reserve returns a Reservation which is not OK if the bucket is
+// full.
+func NewLeakyBucket(interval time.Duration, capacity int) *LeakyBucket {
+	if interval <= 0 {
+		panic("ratelimit: non-positive interval")
+	}
+	if capacity < 1 {
+		panic("ratelimit: capacity less than 1")
+	}
+	return &LeakyBucket{
+		interval: interval,
+		capacity: capacity,
+		now:      time.Now,
+		mu:       &sync.Mutex{},
+	}
+}
+
+// Allow implements Limiter.
+func (b *LeakyBucket) Allow() bool {
+	return b.reserve(false).ok
+}
+
+// Reserve implements Limiter.
+func (b *LeakyBucket) Reserve() *Reservation {
+	return b.reserve(true)
+}
+
+// Wait implements Limiter.
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	return wait(ctx, b.Reserve)
+}
+
+// Len returns the number of events queued in the bucket, which are reserved but not permitted yet.
+func (b *LeakyBucket) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queued(b.now())
+}
+
+// queued returns the number of events queued at now. It should be called with b.mu held.
+func (b *LeakyBucket) queued(now time.Time) int {
+	if !b.next.After(now) {
+		return 0
+	}
+	// The last interval before next belongs to the latest permit, which may be permitted already
+	return int((b.next.Sub(now)+b.interval-1)/b.interval) - 1
+}
+
+func (b *LeakyBucket) reserve(delay bool) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	r := &Reservation{at: now, now: b.now, cancel: b.cancel}
+	if b.next.After(now) {
+		if !delay || b.queued(now) >= b.capacity {
+			return r
+		}
+		r.at = b.next
+	}
+	b.next = r.at.Add(b.interval)
+	r.ok = true
+	return r
+}
+
+func (b *LeakyBucket) cancel(r *Reservation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !now.Before(r.at) {
+		return // already permitted
+	}
+	// Only the latest reservation can be returned, as later ones keep their times and events must
+	// stay an interval apart
+	if r.at.Add(b.interval).Equal(b.next) {
+		b.next = r.at
+	}
+}
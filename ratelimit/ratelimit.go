@@ -0,0 +1,160 @@
+// Package ratelimit provides token bucket, leaky bucket and sliding-window log rate limiters, and
+// keyed limiters for per-tenant limits. All limiters implement goproc.Limiter, which can be used to
+// pace goroutines started by a goproc.Controller through Controller.WithRateLimit.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrRejected is returned by Wait if the event can never be permitted by the limiter, e.g. the
+	// leaky bucket is full.
+	ErrRejected = errors.New("ratelimit: event is rejected")
+	// ErrExceedsDeadline is returned by Wait if the event would be permitted after the deadline of
+	// the context.
+	ErrExceedsDeadline = errors.New("ratelimit: wait would exceed context deadline")
+)
+
+// Limiter defines the common interface of rate limiters.
+type Limiter interface {
+	// Allow reports whether an event is permitted now, and consumes the permit if it is.
+	Allow() bool
+	// Reserve reserves a permit for an event, which may be delayed. The permit is consumed unless
+	// the Reservation is cancelled before its time.
+	Reserve() *Reservation
+	// Wait blocks until an event is permitted, or returns an error if ctx is done before that.
+	Wait(ctx context.Context) error
+}
+
+// Reservation is a permit reserved for an event at a time.
+type Reservation struct {
+	ok       bool
+	at       time.Time
+	now      func() time.Time
+	cancel   func(r *Reservation) // returns the permit to the limiter, called at most once
+	canceled int32
+}
+
+// OK reports whether the permit is reserved. A Reservation which is not OK can't be waited for.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Time returns the time when the event is permitted.
+func (r *Reservation) Time() time.Time {
+	return r.at
+}
+
+// Delay returns the duration until the event is permitted, which is 0 if it's permitted now.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	if d := r.at.Sub(r.now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel returns the permit to the limiter if the event is not permitted yet, so that it can be
+// reserved by other events. Note that LeakyBucket and SlidingWindowLog only
+// take back their latest reservations.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.cancel == nil || !atomic.CompareAndSwapInt32(&r.canceled, 0, 1) {
+		return
+	}
+	r.cancel(r)
+}
+
+// wait waits for the Reservation returned by reserve, which is cancelled if ctx is done before the
+// event is permitted.
+func wait(ctx context.Context, reserve func() *Reservation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := reserve()
+	if !r.OK() {
+		return ErrRejected
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.at) {
+		r.Cancel()
+		return ErrExceedsDeadline
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Keyed is a set of limiters by key, e.g. per-tenant limiters. Limiters are created on first use
+// of their keys.
+type Keyed struct {
+	newLimiter func(key string) Limiter
+	mu         *sync.Mutex
+	limiters   map[string]Limiter
+}
+
+// NewKeyed creates a new Keyed, which creates the limiter of a key by newLimiter.
+func NewKeyed(newLimiter func(key string) Limiter) *Keyed {
+	return &Keyed{
+		newLimiter: newLimiter,
+		mu:         &sync.Mutex{},
+		limiters:   make(map[string]Limiter),
+	}
+}
+
+// Get returns the limiter of key, which may be passed to goproc.Controller.WithRateLimit.
+func (k *Keyed) Get(key string) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	l, ok := k.limiters[key]
+	if !ok {
+		l = k.newLimiter(key)
+		k.limiters[key] = l
+	}
+	return l
+}
+
+// Allow calls Allow of the limiter of key.
+func (k *Keyed) Allow(key string) bool {
+	return k.Get(key).Allow()
+}
+
+// Reserve calls Reserve of the limiter of key.
+func (k *Keyed) Reserve(key string) *Reservation {
+	return k.Get(key).Reserve()
+}
+
+// Wait calls Wait of the limiter of key.
+func (k *Keyed) Wait(ctx context.Context, key string) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// Remove removes the limiter of key, e.g. for an inactive tenant. A new limiter is created if the
+// key is used again.
+func (k *Keyed) Remove(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.limiters, key)
+}
+
+// Len returns the number of keys with limiters.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
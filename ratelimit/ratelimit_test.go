@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTokenBucket(t *testing.T) {
+	Convey("With token bucket setup", t, func(c C) {
+		clock := &testClock{now: time.Now()}
+		b := NewTokenBucket(10, 3) // a token per 100ms
+		b.now = clock.Now
+
+		Convey("Test allow", func() {
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeFalse)
+			clock.Advance(100 * time.Millisecond)
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeFalse)
+			clock.Advance(time.Hour) // refilled up to burst
+			for i := 0; i < 3; i++ {
+				So(b.Allow(), ShouldBeTrue)
+			}
+			So(b.Allow(), ShouldBeFalse)
+		})
+		Convey("Test reserve", func() {
+			for i := 0; i < 3; i++ {
+				So(b.Reserve().Delay(), ShouldEqual, 0)
+			}
+			r1, r2 := b.Reserve(), b.Reserve()
+			So(r1.OK(), ShouldBeTrue)
+			So(r1.Delay(), ShouldEqual, 100*time.Millisecond)
+			So(r2.Delay(), ShouldEqual, 200*time.Millisecond)
+			r2.Cancel()
+			r2.Cancel() // no-op
+			So(b.Reserve().Delay(), ShouldEqual, 200*time.Millisecond)
+			clock.Advance(100 * time.Millisecond)
+			So(r1.Delay(), ShouldEqual, 0)
+			r1.Cancel() // already permitted
+			So(b.Allow(), ShouldBeFalse)
+		})
+	})
+}
+
+func TestLeakyBucket(t *testing.T) {
+	Convey("With leaky bucket setup", t, func(c C) {
+		clock := &testClock{now: time.Now()}
+		b := NewLeakyBucket(100*time.Millisecond, 2)
+		b.now = clock.Now
+
+		Convey("Test allow", func() {
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeFalse) // no burst
+			clock.Advance(50 * time.Millisecond)
+			So(b.Allow(), ShouldBeFalse)
+			clock.Advance(50 * time.Millisecond)
+			So(b.Allow(), ShouldBeTrue)
+		})
+		Convey("Test reserve", func() {
+			So(b.Reserve().Delay(), ShouldEqual, 0)
+			r1, r2 := b.Reserve(), b.Reserve()
+			So(r1.Delay(), ShouldEqual, 100*time.Millisecond)
+			So(r2.Delay(), ShouldEqual, 200*time.Millisecond)
+			So(b.Len(), ShouldEqual, 2)
+			So(b.Reserve().OK(), ShouldBeFalse) // full
+			r1.Cancel()                         // not the latest
+			So(b.Reserve().OK(), ShouldBeFalse)
+			r2.Cancel()
+			So(b.Len(), ShouldEqual, 1)
+			So(b.Reserve().Delay(), ShouldEqual, 200*time.Millisecond)
+			clock.Advance(150 * time.Millisecond)
+			So(b.Len(), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	Convey("With sliding window log setup", t, func(c C) {
+		clock := &testClock{now: time.Now()}
+		w := NewSlidingWindowLog(3, time.Second)
+		w.now = clock.Now
+
+		Convey("Test allow", func() {
+			So(w.Allow(), ShouldBeTrue)
+			clock.Advance(500 * time.Millisecond)
+			So(w.Allow(), ShouldBeTrue)
+			So(w.Allow(), ShouldBeTrue)
+			So(w.Allow(), ShouldBeFalse)
+			clock.Advance(500 * time.Millisecond) // the first permit leaves the window
+			So(w.Allow(), ShouldBeTrue)
+			So(w.Allow(), ShouldBeFalse)
+			So(w.Len(), ShouldEqual, 3)
+		})
+		Convey("Test reserve", func() {
+			for i := 0; i < 3; i++ {
+				So(w.Reserve().Delay(), ShouldEqual, 0)
+				clock.Advance(100 * time.Millisecond)
+			}
+			r1, r2 := w.Reserve(), w.Reserve()
+			So(r1.Delay(), ShouldEqual, 700*time.Millisecond)
+			So(r2.Delay(), ShouldEqual, 800*time.Millisecond)
+			r1.Cancel() // not the latest
+			So(w.Len(), ShouldEqual, 5)
+			r2.Cancel()
+			So(w.Reserve().Delay(), ShouldEqual, 800*time.Millisecond)
+			So(w.Len(), ShouldEqual, 5)
+		})
+	})
+}
+
+func TestWait(t *testing.T) {
+	Convey("Test wait", t, func(c C) {
+		const testInterval = 20 * time.Millisecond
+		b := NewLeakyBucket(testInterval, 10)
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			So(b.Wait(context.Background()), ShouldBeNil)
+		}
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 3*testInterval)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testInterval/2)
+		defer cancel()
+		So(b.Wait(ctx), ShouldEqual, ErrExceedsDeadline)
+		So(b.Len(), ShouldEqual, 0) // cancelled
+
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(testInterval / 2)
+			cancel()
+		}()
+		So(b.Wait(ctx), ShouldEqual, context.Canceled)
+		So(b.Wait(ctx), ShouldEqual, context.Canceled)
+
+		So(NewLeakyBucket(time.Hour, 1).Wait(context.Background()), ShouldBeNil)
+		full := NewLeakyBucket(time.Hour, 1)
+		full.Reserve()
+		full.Reserve()
+		So(full.Wait(context.Background()), ShouldEqual, ErrRejected)
+	})
+}
+
+func TestKeyed(t *testing.T) {
+	Convey("Test keyed limiters", t, func(c C) {
+		k := NewKeyed(func(key string) Limiter {
+			if key == "premium" {
+				return NewTokenBucket(1, 2)
+			}
+			return NewTokenBucket(1, 1)
+		})
+		So(k.Allow("free"), ShouldBeTrue)
+		So(k.Allow("free"), ShouldBeFalse)
+		So(k.Allow("premium"), ShouldBeTrue)
+		So(k.Allow("premium"), ShouldBeTrue)
+		So(k.Allow("premium"), ShouldBeFalse)
+		So(k.Reserve("other").OK(), ShouldBeTrue)
+		So(k.Get("free"), ShouldEqual, k.Get("free"))
+		So(k.Len(), ShouldEqual, 3)
+		k.Remove("free")
+		So(k.Len(), ShouldEqual, 2)
+		So(k.Wait(context.Background(), "free"), ShouldBeNil)
+	})
+}
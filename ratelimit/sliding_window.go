@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLog is a sliding-window log limiter, which logs the time of each permit, and permits
+// at most limit events in any window of a duration. It's exact at the cost of memory linear to
+// limit.
+type SlidingWindowLog struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu  *sync.Mutex
+	log []time.Time // ascending times of permits in the last window, including reserved ones
+}
+
+// NewSlidingWindowLog creates a new SlidingWindowLog which permits at most limit events in any
+// window.
+func NewSlidingWindowLog(limit int, window time.Duration) *SlidingWindowLog {
+	if limit < 1 {
+		panic("ratelimit: limit less than 1")
+	}
+	if window <= 0 {
+		panic("ratelimit: non-positive window")
+	}
+	return &SlidingWindowLog{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		mu:     &sync.Mutex{},
+	}
+}
+
+// Allow implements Limiter.
+func (w *SlidingWindowLog) Allow() bool {
+	return w.reserve(false).ok
+}
+
+// Reserve implements Limiter.
+func (w *SlidingWindowLog) Reserve() *Reservation {
+	return w.reserve(true)
+}
+
+// Wait implements Limiter.
+func (w *SlidingWindowLog) Wait(ctx context.Context) error {
+	return wait(ctx, w.Reserve)
+}
+
+// Len returns the number of permits in the log, including reserved ones.
+func (w *SlidingWindowLog) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.prune(w.now())
+	return len(w.log)
+}
+
+// prune drops permits out of the window ending at now. It should be called with w.mu held.
+func (w *SlidingWindowLog) prune(now time.Time) {
+	i := 0
+	for i < len(w.log) && !w.log[i].Add(w.window).After(now) {
+		i++
+	}
+	if i > 0 {
+		w.log = append(w.log[:0], w.log[i:]...)
+	}
+}
+
+func (w *SlidingWindowLog) reserve(delay bool) *Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	w.prune(now)
+	r := &Reservation{at: now, now: w.now, cancel: w.cancel}
+	if len(w.log) >= w.limit {
+		if !delay {
+			return r
+		}
+		// The limit-th latest permit must have left the window
+		r.at = w.log[len(w.log)-w.limit].Add(w.window)
+	}
+	w.log = append(w.log, r.at)
+	r.ok = true
+	return r
+}
+
+func (w *SlidingWindowLog) cancel(r *Reservation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.now().Before(r.at) {
+		return // already permitted
+	}
+	// Only the latest reservation can be returned, as later ones are computed from the log
+	if n := len(w.log); n > 0 && w.log[n-1].Equal(r.at) {
+		w.log = w.log[:n-1]
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token bucket limiter, which refills tokens at a rate up to a burst size, and
+// permits an event per token. It allows bursts of up to burst events.
+type TokenBucket struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu     *sync.Mutex
+	tokens float64 // may be negative for reserved future tokens
+	last   time.Time
+}
+
+// NewTokenBucket creates a new TokenBucket with rate tokens per second and burst size, which starts
+// full.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 {
+		panic("ratelimit: non-positive rate")
+	}
+	if burst < 1 {
+		panic("ratelimit: burst less than 1")
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		now:    time.Now,
+		mu:     &sync.Mutex{},
+		tokens: float64(burst),
+	}
+}
+
+// Allow implements Limiter.
+func (b *TokenBucket) Allow() bool {
+	return b.reserve(false).ok
+}
+
+// Reserve implements Limiter.
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.reserve(true)
+}
+
+// Wait implements Limiter.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return wait(ctx, b.Reserve)
+}
+
+// advance refills tokens until now. It should be called with b.mu held.
+func (b *TokenBucket) advance(now time.Time) {
+	if now.After(b.last) {
+		if !b.last.IsZero() {
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+		}
+		b.last = now
+	}
+}
+
+func (b *TokenBucket) reserve(delay bool) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.advance(now)
+	r := &Reservation{at: now, now: b.now, cancel: b.cancel}
+	if b.tokens < 1 {
+		if !delay {
+			return r
+		}
+		r.at = now.Add(time.Duration((1 - b.tokens) / b.rate * float64(time.Second)))
+	}
+	b.tokens--
+	r.ok = true
+	return r
+}
+
+func (b *TokenBucket) cancel(r *Reservation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !now.Before(r.at) {
+		return // already permitted
+	}
+	b.advance(now)
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,254 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrRetrierClosed is returned for tasks which are pending when Retrier is closed, or submitted
+// after that.
+var ErrRetrierClosed = errors.New("goproc: retrier is closed")
+
+// Backoff defines the function type returning the delay before the n-th retry, n >= 1, given the
+// previous delay, which is 0 before the first retry.
+type Backoff func(n int, prev time.Duration) time.Duration
+
+// ConstantBackoff returns a Backoff which always delays d.
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(n int, prev time.Duration) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff returns a Backoff which delays base and doubles the delay on each retry, up to
+// max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(n int, prev time.Duration) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// FibonacciBackoff returns a Backoff which delays base times the n-th Fibonacci number, up to max.
+func FibonacciBackoff(base, max time.Duration) Backoff {
+	return func(n int, prev time.Duration) time.Duration {
+		a, b := base, base
+		for i := 1; i < n && a < max; i++ {
+			a, b = b, a+b
+		}
+		if a > max {
+			a = max
+		}
+		return a
+	}
+}
+
+// DecorrelatedJitterBackoff returns a Backoff which delays a random duration between base and 3
+// times the previous delay, up to max.
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return func(n int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		d := base
+		if upper := 3 * prev; upper > base {
+			d += time.Duration(rand.Int63n(int64(upper - base)))
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// RetryTask defines the function type of tasks for Retrier, which is called with the attempt
+// number from 1.
+type RetryTask func(ctx context.Context, attempt int) error
+
+// RetryPolicy configures Retrier.
+type RetryPolicy struct {
+	// Backoff computes retry delays, it must not be nil.
+	Backoff Backoff
+	// MaxAttempts limits the number of attempts including the first one, 0 means no limit.
+	MaxAttempts int
+	// MaxElapsed limits the time from the first attempt to the last one, 0 means no limit. A retry
+	// is not scheduled if it would start later.
+	MaxElapsed time.Duration
+	// Retryable classifies errors, nil means all errors are retryable.
+	Retryable func(err error) bool
+	// OnAttempt is called after each attempt with its result, it's optional.
+	OnAttempt func(attempt int, err error)
+	// OnRetry is called before scheduling a retry of a failed attempt with the delay, it's
+	// optional.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// RetryError is returned for tasks failed after retrying.
+type RetryError struct {
+	Attempts int
+	Elapsed  time.Duration
+	Err      error // error of the last attempt
+}
+
+// Error implements error.
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("goproc: failed after %d attempts in %v: %v", e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Retrier runs tasks with retries by RetryPolicy. Delayed retries are scheduled by a TimeoutChan,
+// and attempts are executed on goroutines owned by the Retrier Controller.
+type Retrier struct {
+	policy RetryPolicy
+	ctrl   *Controller
+	tc     *TimeoutChan
+
+	mu      *sync.Mutex
+	closed  bool
+	pending map[*retryJob]struct{}
+}
+
+type retryJob struct {
+	task    RetryTask
+	result  chan error
+	start   time.Time
+	attempt int
+	delay   time.Duration // delay before the current attempt
+	at      time.Time
+}
+
+func (j *retryJob) Deadline() time.Time {
+	return j.at
+}
+
+// NewRetrier creates a new Retrier, retries are scheduled within resolution.
+func NewRetrier(ctx context.Context, resolution time.Duration, policy RetryPolicy) *Retrier {
+	if policy.Backoff == nil {
+		panic("goproc: nil backoff")
+	}
+	r := &Retrier{
+		policy:  policy,
+		ctrl:    NewController(ctx, "Retrier"),
+		tc:      NewTimeoutChan(ctx, resolution, 0),
+		mu:      &sync.Mutex{},
+		pending: make(map[*retryJob]struct{}),
+	}
+	r.ctrl.Go(r.retryProcess)
+	return r
+}
+
+// Do starts task immediately, and retries it by policy if it fails. The returned channel receives
+// the final result: nil on success, or a *RetryError, or ErrRetrierClosed if Retrier is closed
+// before the task is done.
+func (r *Retrier) Do(task RetryTask) <-chan error {
+	j := &retryJob{task: task, result: make(chan error, 1), start: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.ctrl.Die() {
+		j.result <- ErrRetrierClosed
+		return j.result
+	}
+	r.pending[j] = struct{}{}
+	r.ctrl.Go(func(ctx context.Context) { r.run(ctx, j) })
+	return j.result
+}
+
+// Run is the blocking version of Retrier.Do, which also returns ctx.Err() if ctx is done before
+// the task - which keeps going on - is done.
+func (r *Retrier) Run(ctx context.Context, task RetryTask) error {
+	select {
+	case err := <-r.Do(task):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close cancels running attempts and stops retrying. Running attempts are done with their errors,
+// and tasks waiting for retries are done with ErrRetrierClosed.
+func (r *Retrier) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.ctrl.Shutdown()
+	r.tc.Shutdown()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for j := range r.pending {
+		delete(r.pending, j)
+		j.result <- ErrRetrierClosed
+	}
+}
+
+func (r *Retrier) retryProcess(ctx context.Context) {
+	for {
+		select {
+		case d := <-r.tc.Out:
+			j := d.(*retryJob)
+			r.mu.Lock()
+			if !r.closed && !r.ctrl.Die() {
+				r.ctrl.Go(func(ctx context.Context) { r.run(ctx, j) })
+			}
+			r.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run runs an attempt of j, and schedules a retry or finishes j.
+func (r *Retrier) run(ctx context.Context, j *retryJob) {
+	j.attempt++
+	err := j.task(ctx, j.attempt)
+	if r.policy.OnAttempt != nil {
+		r.policy.OnAttempt(j.attempt, err)
+	}
+	if err == nil {
+		r.finish(j, nil)
+		return
+	}
+	fail := &RetryError{Attempts: j.attempt, Elapsed: time.Since(j.start), Err: err}
+	if ctx.Err() != nil ||
+		(r.policy.Retryable != nil && !r.policy.Retryable(err)) ||
+		(r.policy.MaxAttempts > 0 && j.attempt >= r.policy.MaxAttempts) {
+		r.finish(j, fail)
+		return
+	}
+	delay := r.policy.Backoff(j.attempt, j.delay)
+	if r.policy.MaxElapsed > 0 && fail.Elapsed+delay > r.policy.MaxElapsed {
+		r.finish(j, fail)
+		return
+	}
+	if r.policy.OnRetry != nil {
+		r.policy.OnRetry(j.attempt, err, delay)
+	}
+	j.delay = delay
+	j.at = time.Now().Add(delay)
+	if r.tc.Push(j) != nil {
+		r.finish(j, ErrRetrierClosed)
+	}
+}
+
+// finish sends the final result of j, unless it's finished by Retrier.Close.
+func (r *Retrier) finish(j *retryJob, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[j]; ok {
+		delete(r.pending, j)
+		j.result <- err
+	}
+}
@@ -0,0 +1,128 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackoff(t *testing.T) {
+	Convey("Test backoff strategies", t, func(c C) {
+		const (
+			base = 10 * time.Millisecond
+			max  = 100 * time.Millisecond
+		)
+		delays := func(b Backoff, n int) []time.Duration {
+			var (
+				out  []time.Duration
+				prev time.Duration
+			)
+			for i := 1; i <= n; i++ {
+				prev = b(i, prev)
+				out = append(out, prev)
+			}
+			return out
+		}
+		So(delays(ConstantBackoff(base), 3), ShouldResemble, []time.Duration{base, base, base})
+		So(delays(ExponentialBackoff(base, max), 6), ShouldResemble, []time.Duration{
+			base, 2 * base, 4 * base, 8 * base, max, max})
+		So(delays(FibonacciBackoff(base, max), 7), ShouldResemble, []time.Duration{
+			base, base, 2 * base, 3 * base, 5 * base, 8 * base, max})
+		var prev time.Duration
+		for i, d := range delays(DecorrelatedJitterBackoff(base, max), 100) {
+			So(d, ShouldBeBetweenOrEqual, base, max)
+			if i > 0 {
+				So(d, ShouldBeLessThanOrEqualTo, 3*prev)
+			}
+			prev = d
+		}
+	})
+}
+
+func TestRetrier(t *testing.T) {
+	Convey("With retrier setup", t, func(c C) {
+		const testDelay = 20 * time.Millisecond
+		var (
+			errTemporary = errors.New("temporary")
+			errPermanent = errors.New("permanent")
+			mu           sync.Mutex
+			attempts     []int
+			retries      []time.Duration
+			policy       = RetryPolicy{
+				Backoff:     ExponentialBackoff(testDelay, time.Second),
+				MaxAttempts: 4,
+				Retryable:   func(err error) bool { return err != errPermanent },
+				OnAttempt: func(attempt int, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					attempts = append(attempts, attempt)
+				},
+				OnRetry: func(attempt int, err error, delay time.Duration) {
+					mu.Lock()
+					defer mu.Unlock()
+					retries = append(retries, delay)
+				},
+			}
+		)
+		failUntil := func(n int, err error) RetryTask {
+			return func(ctx context.Context, attempt int) error {
+				if attempt < n {
+					return err
+				}
+				return nil
+			}
+		}
+
+		Convey("Test success after retries", func() {
+			r := NewRetrier(context.Background(), time.Millisecond, policy)
+			defer r.Close()
+			start := time.Now()
+			So(r.Run(context.Background(), failUntil(3, errTemporary)), ShouldBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 3*testDelay)
+			So(attempts, ShouldResemble, []int{1, 2, 3})
+			So(retries, ShouldResemble, []time.Duration{testDelay, 2 * testDelay})
+		})
+		Convey("Test max attempts", func() {
+			r := NewRetrier(context.Background(), time.Millisecond, policy)
+			defer r.Close()
+			err := <-r.Do(failUntil(10, errTemporary))
+			fmt.Println(err)
+			var rerr *RetryError
+			So(errors.As(err, &rerr), ShouldBeTrue)
+			So(rerr.Attempts, ShouldEqual, 4)
+			So(errors.Is(err, errTemporary), ShouldBeTrue)
+		})
+		Convey("Test non-retryable error", func() {
+			r := NewRetrier(context.Background(), time.Millisecond, policy)
+			defer r.Close()
+			err := <-r.Do(failUntil(10, errPermanent))
+			So(errors.Is(err, errPermanent), ShouldBeTrue)
+			So(attempts, ShouldResemble, []int{1})
+			So(retries, ShouldBeEmpty)
+		})
+		Convey("Test max elapsed time", func() {
+			policy.MaxAttempts = 0
+			policy.MaxElapsed = 4 * testDelay
+			r := NewRetrier(context.Background(), time.Millisecond, policy)
+			defer r.Close()
+			err := <-r.Do(failUntil(10, errTemporary))
+			So(errors.Is(err, errTemporary), ShouldBeTrue)
+			So(err.(*RetryError).Attempts, ShouldEqual, 3) // 0 + 20 + 40 < 80 < 0 + 20 + 40 + 80
+			So(err.(*RetryError).Elapsed, ShouldBeLessThan, policy.MaxElapsed)
+		})
+		Convey("Test close", func() {
+			policy.Backoff = ConstantBackoff(time.Hour)
+			r := NewRetrier(context.Background(), time.Millisecond, policy)
+			result := r.Do(failUntil(10, errTemporary))
+			time.Sleep(testDelay)
+			r.Close()
+			So(<-result, ShouldEqual, ErrRetrierClosed)
+			So(<-r.Do(failUntil(1, nil)), ShouldEqual, ErrRetrierClosed)
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSingleFlightClosed is returned for calls made after SingleFlight is closed.
+var ErrSingleFlightClosed = errors.New("goproc: single flight is closed")
+
+// FlightFunc defines the function type of executions coalesced by SingleFlight.
+type FlightFunc func(ctx context.Context) (interface{}, error)
+
+// singleCall is an in-flight execution of a key.
+type singleCall struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+	shared  bool
+	done    chan struct{}
+	value   interface{}
+	err     error
+}
+
+// SingleFlight coalesces concurrent calls of the same key: the first caller starts an execution on
+// a goroutine owned by the SingleFlight Controller, and later callers wait for its result. The
+// execution is cancelled only if all of its callers give up. Successful results may be cached for a
+// short window by a TTLCache, which serves calls of the key without executions.
+type SingleFlight struct {
+	ctrl   *Controller
+	cache  *TTLCache
+	window time.Duration
+
+	mu     *sync.Mutex
+	closed bool
+	calls  map[string]*singleCall
+}
+
+// NewSingleFlight creates a new SingleFlight, which caches successful results for window, within
+// resolution. Non-positive window disables caching.
+func NewSingleFlight(ctx context.Context, resolution, window time.Duration) *SingleFlight {
+	s := &SingleFlight{
+		ctrl:   NewController(ctx, "SingleFlight"),
+		window: window,
+		mu:     &sync.Mutex{},
+		calls:  make(map[string]*singleCall),
+	}
+	if window > 0 {
+		s.cache = NewTTLCache(ctx, resolution, 0, nil)
+	}
+	return s
+}
+
+// Do returns the result of fn for key, which is shared by concurrent calls of key: fn is executed
+// only if there is no execution of key in flight or cached result, otherwise the caller waits for
+// the in-flight one. The shared result tells whether the result is also returned to other callers,
+// or from the cache.
+//
+// If ctx is done before the result, Do returns ctx.Err() and the caller gives up the execution,
+// which is cancelled if all of its callers give up - a later call of key starts a new execution
+// then.
+func (s *SingleFlight) Do(ctx context.Context, key string, fn FlightFunc) (value interface{}, err error, shared bool) {
+	if err := ctx.Err(); err != nil {
+		return nil, err, false
+	}
+	call, err, shared := s.join(key, fn)
+	if call == nil {
+		return nil, err, shared
+	}
+	select {
+	case <-call.done:
+		return call.value, call.err, call.shared
+	case <-ctx.Done():
+		s.leave(key, call)
+		return nil, ctx.Err(), false
+	}
+}
+
+// Forget drops the in-flight execution and the cached result of key, so that the next call of key
+// starts a new execution. Callers waiting for the dropped execution still get its result.
+func (s *SingleFlight) Forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.calls, key)
+	if s.cache != nil {
+		s.cache.Delete(key)
+	}
+}
+
+// InFlight returns the number of keys with executions in flight.
+func (s *SingleFlight) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// Close cancels in-flight executions and waits for them to return, their callers get the results
+// as usual. Calls after Close return ErrSingleFlightClosed.
+func (s *SingleFlight) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.ctrl.Shutdown()
+	if s.cache != nil {
+		s.cache.Close()
+	}
+}
+
+// join returns the in-flight execution of key, or starts a new one. It returns a nil call with the
+// result if key is served by the cache or s is closed.
+func (s *SingleFlight) join(key string, fn FlightFunc) (call *singleCall, err error, shared bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.ctrl.Die() {
+		return nil, ErrSingleFlightClosed, false
+	}
+	if s.cache != nil {
+		if value, ok := s.cache.Get(key); ok {
+			call = &singleCall{value: value, shared: true, done: make(chan struct{})}
+			close(call.done)
+			return call, nil, true
+		}
+	}
+	if call, ok := s.calls[key]; ok {
+		call.waiters++
+		call.shared = true
+		return call, nil, true
+	}
+	call = &singleCall{waiters: 1, done: make(chan struct{})}
+	call.ctx, call.cancel = context.WithCancel(s.ctrl.ctx)
+	s.calls[key] = call
+	s.ctrl.Go(func(ctx context.Context) { s.execute(key, call, fn) })
+	return call, nil, false
+}
+
+// leave gives up call of key for a caller, and cancels the execution if all callers give up.
+func (s *SingleFlight) leave(key string, call *singleCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if call.waiters--; call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if s.calls[key] == call {
+		delete(s.calls, key)
+	}
+}
+
+func (s *SingleFlight) execute(key string, call *singleCall, fn FlightFunc) {
+	value, err := fn(call.ctx)
+	s.mu.Lock()
+	call.value, call.err = value, err
+	if s.calls[key] == call {
+		delete(s.calls, key)
+		if err == nil && s.cache != nil {
+			s.cache.Set(key, value, s.window)
+		}
+	}
+	s.mu.Unlock()
+	call.cancel()
+	close(call.done)
+}
@@ -0,0 +1,129 @@
+package goproc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSingleFlight(t *testing.T) {
+	Convey("With single flight setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testDelay      = 50 * time.Millisecond
+			testCallers    = 10
+		)
+		var (
+			executions int32
+			release    = make(chan struct{})
+			cancelled  = make(chan struct{}, 1)
+			load       = func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&executions, 1)
+				select {
+				case <-release:
+					return "value", nil
+				case <-ctx.Done():
+					cancelled <- struct{}{}
+					return nil, ctx.Err()
+				}
+			}
+		)
+
+		Convey("Test coalescing", func() {
+			s := NewSingleFlight(context.Background(), testResolution, 0)
+			defer s.Close()
+			var (
+				wg     sync.WaitGroup
+				shared int32
+			)
+			for i := 0; i < testCallers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					value, err, ok := s.Do(context.Background(), "a", load)
+					c.So(err, ShouldBeNil)
+					c.So(value, ShouldEqual, "value")
+					if ok {
+						atomic.AddInt32(&shared, 1)
+					}
+				}()
+			}
+			time.Sleep(testDelay)
+			So(s.InFlight(), ShouldEqual, 1)
+			close(release)
+			wg.Wait()
+			So(atomic.LoadInt32(&executions), ShouldEqual, 1)
+			So(atomic.LoadInt32(&shared), ShouldEqual, testCallers)
+			So(s.InFlight(), ShouldEqual, 0)
+
+			_, _, ok := s.Do(context.Background(), "a", load) // not cached
+			So(ok, ShouldBeFalse)
+			So(atomic.LoadInt32(&executions), ShouldEqual, 2)
+		})
+		Convey("Test result caching", func() {
+			s := NewSingleFlight(context.Background(), testResolution, testDelay)
+			defer s.Close()
+			close(release)
+			for i := 0; i < 3; i++ {
+				value, err, _ := s.Do(context.Background(), "a", load)
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, "value")
+			}
+			So(atomic.LoadInt32(&executions), ShouldEqual, 1)
+			time.Sleep(testDelay + 4*testResolution)
+			s.Do(context.Background(), "a", load)
+			So(atomic.LoadInt32(&executions), ShouldEqual, 2)
+			s.Forget("a")
+			s.Do(context.Background(), "a", load)
+			So(atomic.LoadInt32(&executions), ShouldEqual, 3)
+
+			errTest := errors.New("test")
+			fail := func(ctx context.Context) (interface{}, error) { return nil, errTest }
+			_, err, _ := s.Do(context.Background(), "b", fail)
+			So(err, ShouldEqual, errTest)
+			_, err, _ = s.Do(context.Background(), "b", load) // errors are not cached
+			So(err, ShouldBeNil)
+		})
+		Convey("Test cancellation", func() {
+			s := NewSingleFlight(context.Background(), testResolution, 0)
+			defer s.Close()
+			ctx1, cancel1 := context.WithCancel(context.Background())
+			ctx2, cancel2 := context.WithTimeout(context.Background(), testDelay)
+			defer cancel2()
+			go func() {
+				time.Sleep(testDelay / 2)
+				cancel1()
+			}()
+			result := make(chan error, 1)
+			go func() {
+				_, err, _ := s.Do(ctx2, "a", load)
+				result <- err
+			}()
+			_, err, _ := s.Do(ctx1, "a", load)
+			So(err, ShouldEqual, context.Canceled)
+			So(cancelled, ShouldBeEmpty) // another caller is waiting
+			So(errors.Is(<-result, context.DeadlineExceeded), ShouldBeTrue)
+			<-cancelled
+			So(atomic.LoadInt32(&executions), ShouldEqual, 1)
+			So(s.InFlight(), ShouldEqual, 0)
+		})
+		Convey("Test close", func() {
+			s := NewSingleFlight(context.Background(), testResolution, testDelay)
+			result := make(chan error, 1)
+			go func() {
+				_, err, _ := s.Do(context.Background(), "a", load)
+				result <- err
+			}()
+			time.Sleep(testDelay / 2)
+			s.Close()
+			So(<-result, ShouldEqual, context.Canceled)
+			_, err, _ := s.Do(context.Background(), "a", load)
+			So(err, ShouldEqual, ErrSingleFlightClosed)
+		})
+	})
+}
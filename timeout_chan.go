@@ -15,22 +15,97 @@ type Deadliner interface {
 
 // TimeoutChanStats contains timeout chan statistics returned from TimeoutChan.Stats().
 type TimeoutChanStats struct {
-	Pushed  int
-	Popped  int
-	Cleared int
+	Pushed       int
+	Popped       int
+	Cleared      int
+	Recovered    int
+	Acked        int
+	Redelivered  int
+	DeadLettered int
+	InFlight     int
+	Dropped      int
+	Early        int
+	Dispatched   int
+	Panicked     int
+	Batches      int
+	Recurred     int // deliveries of recurrences, which are included in Popped
+	ClockJumps   int // wall clock jumps detected
+	Replaced     int // Keyed Deadliners replaced by deduplication
+	Len          int // current number of buffered Deadliners
+	HighWater    int // max number of buffered Deadliners ever
+	Reschedules  int // times that the earliest deadline is changed by a push
+	Wakeups      int // timer wakeups of the pop process while spinning
+	// Lateness is the histogram of time of sending to TimeoutChan.Out (or TimeoutChan.OutBatch)
+	// minus Deadline. Deadliners sent ahead of their deadlines - by OverflowDeliverEarliest policy,
+	// which are counted by Early, or within a batch window - are recorded as 0.
+	Lateness HistogramSnapshot
+	// Blocking is the histogram of time blocked on sending to TimeoutChan.Out (or
+	// TimeoutChan.OutBatch), waiting for consumers.
+	Blocking HistogramSnapshot
 }
 
 // String implements fmt.Stringer.
 func (s TimeoutChanStats) String() string {
-	return fmt.Sprintf("TimeoutChanStats: Pushed=%d Popped=%d Cleared=%d", s.Pushed, s.Popped, s.Cleared)
+	return fmt.Sprintf("TimeoutChanStats: Pushed=%d Popped=%d Cleared=%d Recovered=%d Acked=%d "+
+		"Redelivered=%d DeadLettered=%d InFlight=%d Dropped=%d Early=%d Dispatched=%d Panicked=%d Batches=%d "+
+		"Recurred=%d ClockJumps=%d Replaced=%d Len=%d HighWater=%d Reschedules=%d Wakeups=%d Lateness=(%v) Blocking=(%v)",
+		s.Pushed, s.Popped, s.Cleared, s.Recovered, s.Acked, s.Redelivered, s.DeadLettered, s.InFlight,
+		s.Dropped, s.Early, s.Dispatched, s.Panicked, s.Batches, s.Recurred, s.ClockJumps, s.Replaced,
+		s.Len, s.HighWater, s.Reschedules, s.Wakeups, s.Lateness, s.Blocking)
+}
+
+// TimeoutChanOption configures optional behaviors of TimeoutChan in NewTimeoutChan.
+type TimeoutChanOption func(c *TimeoutChan)
+
+// WithJournal makes TimeoutChan durable with journal j: pushed Deadliners are appended to j and
+// acknowledged once sent to TimeoutChan.Out or cleared, and pending Deadliners in j are recovered
+// on creating TimeoutChan - the overdue ones are sent immediately in deadline order. The codec of
+// j must decode items into Deadliners. TimeoutChan doesn't close j.
+//
+// A Deadliner is recovered at least once: it may be sent again after restart if the process exits
+// before it's acknowledged. Deadliners sent to TimeoutChan.In are still scheduled if journaling
+// fails, use TimeoutChan.Push to get the error.
+func WithJournal(j *Journal) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.journal = j
+	}
+}
+
+// WithAck enables at-least-once delivery: a Deadliner sent to TimeoutChan.Out is held in flight
+// until TimeoutChan.Ack is called with it, or it's sent again after visibility timeout. After
+// maxAttempts deliveries without acknowledgement, the Deadliner is sent to deadLetter instead, or
+// dropped if deadLetter is nil. 0 maxAttempts means no limit.
+//
+// In-flight Deadliners are tracked by equality, so they must be comparable and should not be
+// pushed again before being acknowledged. For durable TimeoutChan, Deadliners are acknowledged to
+// the journal on TimeoutChan.Ack or dead-lettering instead of sending.
+func WithAck(visibility time.Duration, maxAttempts int, deadLetter chan<- Deadliner) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.visibility = visibility
+		c.maxAttempts = maxAttempts
+		c.deadLetter = deadLetter
+		c.inflight = make(map[Deadliner]*inflight)
+	}
+}
+
+// WithLatencyBounds sets the histogram bucket upper bounds of delivery lateness and blocking time
+// in TimeoutChanStats, DefaultLatencyBounds is used by default.
+func WithLatencyBounds(bounds []time.Duration) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.lateness = NewHistogram(bounds)
+		c.blocking = NewHistogram(bounds)
+	}
 }
 
 // TimeoutChan is a type representing a channel for Deadliner objects.
 // TimeoutChan accepts Deadliner from TimeoutChan.In and sends Deadliner to Timeout.Out when its
 // deadline is reached.
+// Like a channel, sending to TimeoutChan.In panics after TimeoutChan is closed, use TimeoutChan.Push
+// and its variants to get ErrClosed instead.
 type TimeoutChan struct {
-	In  chan<- Deadliner
-	Out <-chan Deadliner
+	In       chan<- Deadliner
+	Out      <-chan Deadliner
+	OutBatch <-chan []Deadliner // only available in batch mode
 
 	ctx        context.Context
 	pushCtrl   *Controller
@@ -39,21 +114,68 @@ type TimeoutChan struct {
 	limit      int
 	in         chan Deadliner
 	out        chan Deadliner
+	outBatch   chan []Deadliner
 	resumePush chan interface{}
 	resumePop  chan interface{}
 	reschedule chan interface{}
 	closePush  chan interface{}
+	closing    chan interface{}
+	sendMu     *sync.RWMutex // guards sending to in against closing
+
+	journal     *Journal
+	visibility  time.Duration
+	maxAttempts int
+	deadLetter  chan<- Deadliner
+	overflow    OverflowPolicy
+	onDrop      func(d Deadliner)
+	handler     func(d Deadliner)
+	workers     int
+	recoverFunc Recover
+	batch       int
+	clock       Clock
+	clockPolicy ClockPolicy
+	onJump      func(jump time.Duration)
+	lastWall    int64         // last wall clock reading in checkClock
+	lastMono    time.Duration // last monotonic reading in checkClock
+	dedupPolicy DedupPolicy
+	onReplace   func(replaced Deadliner)
+
+	dispatchCtrl *Controller
 
-	mu      *sync.RWMutex
-	pq      *PriorityQueue
-	pushed  int
-	popped  int
-	cleared int
+	mu           *sync.RWMutex
+	closed       bool
+	pq           *PriorityQueue
+	inflight     map[Deadliner]*inflight
+	keys         map[string]*keySlot
+	earlyQueue   []prioritierWrapper // Deadliners to be sent early by OverflowDeliverEarliest, in order
+	held         prioritierWrapper   // accepted from in, but not admitted when push process stops
+	redeliveries int                 // redelivery entries in pq
+	stale        int                 // redelivery entries in pq of acknowledged Deadliners
+	pushed       int
+	popped       int
+	cleared      int
+	recovered    int
+	acked        int
+	redelivered  int
+	deadLettered int
+	dropped      int
+	early        int
+	dispatched   int
+	panicked     int
+	batches      int
+	recurred     int
+	clockJumps   int
+	replaced     int
+	highWater    int
+	reschedules  int
+	wakeups      int
+	lateness     *Histogram
+	blocking     *Histogram
 }
 
 // NewTimeoutChan creates a new TimeoutChan. With 0 limit an unlimited timeout chan will be
 // returned.
-func NewTimeoutChan(ctx context.Context, resolution time.Duration, limit int) *TimeoutChan {
+func NewTimeoutChan(ctx context.Context, resolution time.Duration, limit int, opts ...TimeoutChanOption) *TimeoutChan {
 	size := limit
 	if limit == 0 {
 		size = 1024
@@ -71,16 +193,38 @@ func NewTimeoutChan(ctx context.Context, resolution time.Duration, limit int) *T
 		limit:      limit,
 		in:         in,
 		out:        out,
-		resumePush: make(chan interface{}),
-		resumePop:  make(chan interface{}),
+		resumePush: make(chan interface{}, 1),
+		resumePop:  make(chan interface{}, 1),
 		reschedule: make(chan interface{}),
 		closePush:  make(chan interface{}),
+		closing:    make(chan interface{}),
+		sendMu:     &sync.RWMutex{},
 
-		mu:      &sync.RWMutex{},
-		pq:      NewPriorityQueue(false, size),
-		pushed:  0,
-		popped:  0,
-		cleared: 0,
+		clock:     SystemClock,
+		mu:        &sync.RWMutex{},
+		pq:        NewPriorityQueue(false, size),
+		pushed:    0,
+		popped:    0,
+		cleared:   0,
+		recovered: 0,
+		lateness:  NewHistogram(DefaultLatencyBounds),
+		blocking:  NewHistogram(DefaultLatencyBounds),
+	}
+	for _, opt := range opts {
+		opt(tc)
+	}
+	if tc.batch > 0 {
+		if tc.inflight != nil || tc.workers > 0 {
+			panic("goproc: batch mode can't be used along with ack or callback mode")
+		}
+		tc.outBatch = make(chan []Deadliner)
+		tc.OutBatch = tc.outBatch
+	}
+	if tc.journal != nil {
+		tc.recover()
+	}
+	if tc.workers > 0 {
+		tc.startDispatch()
 	}
 	tc.popCtrl.Go(tc.popProcess)
 	tc.pushCtrl.Go(tc.pushProcess)
@@ -88,24 +232,82 @@ func NewTimeoutChan(ctx context.Context, resolution time.Duration, limit int) *T
 }
 
 // Push is an alias of TimeoutChan.In <- (in Deadliner), but bypasses background push process for
-// unlimited TimeoutChan.
-func (c *TimeoutChan) Push(in Deadliner) {
-	if c.limit == 0 {
-		c.push(in)
-	} else {
-		c.In <- in
+// unlimited TimeoutChan or non-blocking overflow policy, and returns ErrClosed instead of panicking
+// after TimeoutChan is closed.
+// For durable TimeoutChan, journaling error is returned if in is pushed directly. For OverflowReject
+// policy, ErrFull is returned if in is rejected.
+func (c *TimeoutChan) Push(in Deadliner) error {
+	return c.PushContext(context.Background(), in)
+}
+
+// PushContext is like TimeoutChan.Push, but gives up blocking on a full limited TimeoutChan and
+// returns ctx.Err() when ctx is done.
+func (c *TimeoutChan) PushContext(ctx context.Context, in Deadliner) error {
+	if c.limit == 0 || c.overflow != OverflowBlock {
+		return c.push(in, c.overflow)
+	}
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	select {
+	case <-c.closing:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.in <- in:
+		return nil
+	case <-c.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryPush pushes in without blocking, and reports whether in is accepted. It fails if TimeoutChan
+// is closed, or a limited TimeoutChan with OverflowBlock or OverflowReject policy is full.
+func (c *TimeoutChan) TryPush(in Deadliner) bool {
+	policy := c.overflow
+	if c.limit > 0 && policy == OverflowBlock {
+		policy = overflowTry
 	}
+	err := c.push(in, policy)
+	return err != ErrClosed && err != ErrFull
 }
 
-// Clear clears buffered Deadliners in TimeoutChan.
+// Clear clears buffered Deadliners in TimeoutChan, including the one accepted from TimeoutChan.In
+// but waiting for room of a full limited TimeoutChan. In-flight Deadliners are kept for redelivery,
+// and Deadliners already sent early by OverflowDeliverEarliest policy are still sent.
 func (c *TimeoutChan) Clear() int {
-	c.mu.Lock()
-	defer c.mu.Unlock()
 	c.pushCtrl.Shutdown()
 	c.popCtrl.Shutdown()
-	l := c.pq.Clear()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var redeliveries []Prioritier
+	for _, item := range c.pq.heap {
+		w := item.(prioritierWrapper)
+		if w.flight == nil {
+			c.ackJournal(w.id)
+		} else if c.inflight[w.Deadliner] == w.flight {
+			redeliveries = append(redeliveries, w)
+		}
+	}
+	l := c.pq.Len() - c.redeliveries
+	c.pq.Clear()
+	c.pq.PushAll(redeliveries...)
+	if c.keys != nil {
+		c.keys = make(map[string]*keySlot)
+	}
+	c.redeliveries = len(redeliveries)
+	c.stale = 0
 	if c.limit > 0 && l == c.limit {
-		defer func() { c.resumePush <- nil }() // queue is not full, resume
+		signal(c.resumePush) // queue is not full, resume
+	}
+	if c.held.Deadliner != nil {
+		// Accepted while queue is full, it's cleared as if it's pushed
+		c.ackJournal(c.held.id)
+		c.held = prioritierWrapper{}
+		c.pushed++
+		l++
 	}
 	c.cleared += l
 	c.pushCtrl = NewController(c.ctx, "TimeoutChan Push")
@@ -115,93 +317,485 @@ func (c *TimeoutChan) Clear() int {
 	return l
 }
 
+// Ack acknowledges an in-flight Deadliner received from TimeoutChan.Out with TimeoutChan created
+// by WithAck option, and reports whether item was in flight.
+func (c *TimeoutChan) Ack(item Deadliner) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.inflight[item]
+	if !ok {
+		return false
+	}
+	delete(c.inflight, item)
+	if f.scheduled {
+		c.stale++
+	}
+	c.acked++
+	c.ackJournal(f.id)
+	return true
+}
+
 // Close closes TimeoutChan and waits until all buffered Deadliners in TimeoutChan to be sent and
-// read in TimeoutChan.Out before it returns.
+// read in TimeoutChan.Out before it returns. With WithAck option, it also waits until all
+// in-flight Deadliners to be acknowledged or dead-lettered. In callback mode, it also waits until
+// all callbacks to return.
 func (c *TimeoutChan) Close() {
-	close(c.in)
+	if !c.beginClose() {
+		return
+	}
 	c.pushCtrl.Wait()
 	close(c.closePush)
 	c.popCtrl.Wait()
 	close(c.out)
+	if c.outBatch != nil {
+		close(c.outBatch)
+	}
+	if c.dispatchCtrl != nil {
+		c.dispatchCtrl.Wait()
+	}
 	close(c.resumePush)
 	close(c.resumePop)
 	close(c.reschedule)
 }
 
 // Shutdown closes TimeoutChan and returns immediately, any buffered Deadliners in TimeoutChan will
-// be ignored.
+// be ignored. In callback mode, it waits until running callbacks to return.
 func (c *TimeoutChan) Shutdown() {
-	close(c.in)
+	if !c.beginClose() {
+		return
+	}
 	c.pushCtrl.Shutdown()
 	close(c.closePush)
 	c.popCtrl.Shutdown()
 	close(c.out)
+	if c.outBatch != nil {
+		close(c.outBatch)
+	}
+	if c.dispatchCtrl != nil {
+		c.dispatchCtrl.Shutdown()
+	}
 	close(c.resumePush)
 	close(c.resumePop)
 	close(c.reschedule)
 }
 
+// beginClose rejects subsequent pushes and closes c.in, it returns false if TimeoutChan is already
+// closed.
+func (c *TimeoutChan) beginClose() bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.closed = true
+	close(c.closing)
+	c.mu.Unlock()
+	c.sendMu.Lock() // wait for blocking pushes to return
+	close(c.in)
+	c.sendMu.Unlock()
+	return true
+}
+
 // Stats returns TimeoutChan statistics.
 func (c *TimeoutChan) Stats() TimeoutChanStats {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return TimeoutChanStats{
-		Pushed:  c.pushed,
-		Popped:  c.popped,
-		Cleared: c.cleared,
+		Pushed:       c.pushed,
+		Popped:       c.popped,
+		Cleared:      c.cleared,
+		Recovered:    c.recovered,
+		Acked:        c.acked,
+		Redelivered:  c.redelivered,
+		DeadLettered: c.deadLettered,
+		InFlight:     len(c.inflight),
+		Dropped:      c.dropped,
+		Early:        c.early,
+		Dispatched:   c.dispatched,
+		Panicked:     c.panicked,
+		Batches:      c.batches,
+		Recurred:     c.recurred,
+		ClockJumps:   c.clockJumps,
+		Replaced:     c.replaced,
+		Len:          c.buffered(),
+		HighWater:    c.highWater,
+		Reschedules:  c.reschedules,
+		Wakeups:      c.wakeups,
+		Lateness:     c.lateness.Snapshot(),
+		Blocking:     c.blocking.Snapshot(),
+	}
+}
+
+// Len returns the number of buffered Deadliners in TimeoutChan, excluding in-flight ones.
+func (c *TimeoutChan) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.buffered()
+}
+
+// NextDeadline returns the time of the next scheduled sending in TimeoutChan, which can also be a
+// redelivery with WithAck option, or false if TimeoutChan is empty.
+func (c *TimeoutChan) NextDeadline() (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.pq.Len() == 0 {
+		return time.Time{}, false
+	}
+	return c.pq.Peek().(Deadliner).Deadline(), true
+}
+
+// Snapshot returns buffered Deadliners in TimeoutChan sorted by deadline, without disturbing the
+// scheduling.
+func (c *TimeoutChan) Snapshot() []Deadliner {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshot := make([]Deadliner, 0, c.pq.Len())
+	for it := c.pq.Iterate(); ; {
+		item, ok := it.Next()
+		if !ok {
+			break
+		}
+		if w := item.(prioritierWrapper); w.flight == nil {
+			snapshot = append(snapshot, w.Deadliner) // unwrap
+		}
 	}
+	return snapshot
+}
+
+// buffered returns the number of buffered Deadliners, excluding redelivery entries.
+func (c *TimeoutChan) buffered() int {
+	return c.pq.Len() - c.redeliveries
+}
+
+// live returns the number of entries to be scheduled, excluding stale redelivery entries.
+func (c *TimeoutChan) live() int {
+	return c.pq.Len() - c.stale
 }
 
-func (c *TimeoutChan) len() int {
+// liveLen returns the number of entries to be handled by pop process, including Deadliners to be
+// sent early.
+func (c *TimeoutChan) liveLen() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.pq.Len()
+	return c.live() + len(c.earlyQueue)
 }
 
 func (c *TimeoutChan) peek() (<-chan interface{}, time.Duration) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.reschedule, c.pq.Peek().(Deadliner).Deadline().Sub(time.Now())
+	if c.pq.Len() == 0 {
+		return c.reschedule, c.resolution // emptied by removal since checked
+	}
+	return c.reschedule, time.Duration(c.pq.Peek().(prioritierWrapper).at - c.instant())
 }
 
 type prioritierWrapper struct {
 	Deadliner
+	id         uint64    // journal ID for durable TimeoutChan
+	flight     *inflight // in-flight record for redelivery entry
+	deadline   time.Time // cached deadline, or redelivery time for redelivery entry
+	at         int64     // priority of deadline by clock policy
+	slot       *keySlot  // key slot for Keyed Deadliner with WithDedup option
+	recurrence bool      // whether it's rescheduled by Recurrer.Next
+}
+
+// Deadline overrides Deadliner.Deadline with the cached deadline.
+func (w prioritierWrapper) Deadline() time.Time {
+	return w.deadline
 }
 
 func (w prioritierWrapper) Priority() int64 {
-	return w.Deadline().UnixNano()
+	return w.at
+}
+
+// inflight is the record of an in-flight Deadliner with WithAck option.
+type inflight struct {
+	id        uint64 // journal ID for durable TimeoutChan
+	attempts  int
+	scheduled bool // whether a redelivery entry is in pq
+}
+
+// recover pushes pending Deadliners in journal, it should be called before starting background
+// processes.
+func (c *TimeoutChan) recover() {
+	entries := c.journal.Entries()
+	items := make([]Prioritier, 0, len(entries))
+	for _, e := range entries {
+		if in, ok := e.Item.(Deadliner); ok {
+			items = append(items, c.wrapAt(prioritierWrapper{Deadliner: in, id: e.ID}, in.Deadline()))
+		} else {
+			c.journal.Ack(e.ID) // not recoverable
+		}
+	}
+	c.pq.PushAll(items...)
+	c.recovered = len(items)
+}
+
+// push pushes in with policy directly, bypassing background push process.
+func (c *TimeoutChan) push(in Deadliner, policy OverflowPolicy) error {
+	item, err := c.wrap(in)
+	if aerr := c.admit(item, policy, false); aerr != nil {
+		if aerr == ErrFull && policy == overflowTry {
+			c.ackJournal(item.id)
+		}
+		return aerr
+	}
+	return err
+}
+
+// wrap wraps in for pushing, and appends it to journal for durable TimeoutChan. The wrapped item is
+// still valid on journaling error.
+func (c *TimeoutChan) wrap(in Deadliner) (prioritierWrapper, error) {
+	item := c.wrapAt(prioritierWrapper{Deadliner: in}, in.Deadline())
+	if c.journal == nil {
+		return item, nil
+	}
+	id, err := c.journal.Append(in)
+	item.id = id
+	return item, err
 }
 
-func (c *TimeoutChan) push(in Deadliner) {
+// admit pushes item into queue, and applies policy if queue is full. Items accepted from c.in are
+// admitted even if TimeoutChan is closing. ErrClosed or ErrFull is returned if item is rejected.
+func (c *TimeoutChan) admit(item prioritierWrapper, policy OverflowPolicy, accepted bool) (err error) {
+	var (
+		victim prioritierWrapper
+		admit  = true
+	)
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.pq.Len() == 0 {
-		defer func() { c.resumePop <- nil }()
-	} else {
-		if in.Deadline().Before(c.pq.Peek().(Deadliner).Deadline()) {
-			// Most recent deadline changed, send reschedule notice
-			defer func() {
-				close(c.reschedule)
-				c.reschedule = make(chan interface{})
-			}()
+	if c.closed && !accepted {
+		c.mu.Unlock()
+		c.ackJournal(item.id)
+		return ErrClosed
+	}
+	if replaced, found := c.dedupLocked(item); found {
+		c.mu.Unlock()
+		if c.onReplace != nil {
+			c.onReplace(replaced.Deadliner)
+		}
+		return nil
+	}
+	if c.limit > 0 && policy != OverflowBlock && c.buffered() >= c.limit {
+		if policy == OverflowDeliverEarliest && len(c.earlyQueue) >= c.limit {
+			policy = OverflowDropNewest // Out is not drained in time
+		}
+		victim, admit, err = c.makeRoom(item, policy)
+	}
+	if admit {
+		c.pushLocked(item)
+	}
+	switch {
+	case victim.Deadliner == nil || policy == overflowTry:
+	case policy == OverflowDeliverEarliest:
+		if !admit {
+			c.pushed++ // pushed and sent early
 		}
+		c.countPop(victim)
+		c.early++
+		c.earlyQueue = append(c.earlyQueue, victim)
+		c.wakeLocked()
+	default:
+		c.dropped++
+		c.ackJournal(victim.id)
+	}
+	c.mu.Unlock()
+	if victim.Deadliner != nil && policy != overflowTry && policy != OverflowDeliverEarliest && c.onDrop != nil {
+		c.onDrop(victim.Deadliner)
+	}
+	return
+}
+
+func (c *TimeoutChan) pushLocked(item prioritierWrapper) {
+	if c.live() == 0 {
+		signal(c.resumePop)
+	} else if item.at < c.pq.Peek().(prioritierWrapper).at {
+		c.wakeLocked() // most recent deadline changed
+		c.reschedules++
+	}
+	c.keyLocked(&item)
+	heap.Push(c.pq, item)
+	if !item.recurrence {
+		c.pushed++
+	}
+	if n := c.buffered(); n > c.highWater {
+		c.highWater = n
+	}
+}
+
+func (c *TimeoutChan) pop() prioritierWrapper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item := heap.Pop(c.pq).(prioritierWrapper)
+	if item.flight != nil {
+		c.redeliveries--
+		return item
 	}
-	heap.Push(c.pq, prioritierWrapper{in})
-	c.pushed++
+	c.unkeyLocked(item)
+	if c.limit > 0 && c.buffered() == c.limit-1 {
+		signal(c.resumePush) // queue is not full, resume
+	}
+	c.countPop(item)
+	return item
 }
 
-func (c *TimeoutChan) pop() Deadliner {
+// popEarly pops the first Deadliner to be sent early, it returns false if there is none.
+func (c *TimeoutChan) popEarly() (prioritierWrapper, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.limit > 0 && c.pq.Len() == c.limit {
-		defer func() { c.resumePush <- nil }() // queue is not full, resume
+	if len(c.earlyQueue) == 0 {
+		return prioritierWrapper{}, false
 	}
+	item := c.earlyQueue[0]
+	c.earlyQueue[0] = prioritierWrapper{}
+	c.earlyQueue = c.earlyQueue[1:]
+	return item, true
+}
+
+// wakeLocked wakes pop process up whether it's spinning or suspended, it should be called with c.mu
+// held.
+func (c *TimeoutChan) wakeLocked() {
+	// Send reschedule notice
+	close(c.reschedule)
+	c.reschedule = make(chan interface{})
+	signal(c.resumePop)
+}
+
+// countPop counts item as popped, it should be called with c.mu held.
+func (c *TimeoutChan) countPop(item prioritierWrapper) {
 	c.popped++
-	return heap.Pop(c.pq).(prioritierWrapper).Deadliner // unwrap
+	if item.recurrence {
+		c.recurred++
+	}
+}
+
+// wrapAt sets the cached deadline and its priority of w.
+func (c *TimeoutChan) wrapAt(w prioritierWrapper, deadline time.Time) prioritierWrapper {
+	w.deadline = deadline
+	w.at = c.at(deadline)
+	return w
+}
+
+func (c *TimeoutChan) ackJournal(id uint64) {
+	if c.journal != nil {
+		c.journal.Ack(id) // error is ignored as the item would be recovered
+	}
+}
+
+// track registers item as in flight before sending it, and returns its in-flight record. For a
+// redelivery entry, a nil record is returned if it's stale, and dead is set if it should be
+// dead-lettered.
+func (c *TimeoutChan) track(item prioritierWrapper) (f *inflight, dead bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item.flight == nil {
+		if old, ok := c.inflight[item.Deadliner]; ok && old.scheduled {
+			c.stale++ // replaced
+		}
+		f = &inflight{id: item.id, attempts: 1}
+		c.inflight[item.Deadliner] = f
+		return f, false
+	}
+	if c.inflight[item.Deadliner] != item.flight {
+		c.stale--
+		return nil, false
+	}
+	f = item.flight
+	f.scheduled = false
+	if c.maxAttempts > 0 && f.attempts >= c.maxAttempts {
+		delete(c.inflight, item.Deadliner)
+		c.deadLettered++
+		c.ackJournal(f.id)
+		return f, true
+	}
+	f.attempts++
+	c.redelivered++
+	return f, false
+}
+
+// schedule pushes a redelivery entry for in-flight record f after sending d.
+func (c *TimeoutChan) schedule(d Deadliner, f *inflight) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight[d] != f {
+		return // acknowledged already
+	}
+	f.scheduled = true
+	heap.Push(c.pq, c.wrapAt(prioritierWrapper{Deadliner: d, id: f.id, flight: f}, c.clock.Now().Add(c.visibility)))
+	c.redeliveries++
+}
+
+// deliver sends item to c.out, or c.deadLetter for dead-lettered redelivery entry, or c.outBatch as
+// a single-item batch in batch mode. It returns false if ctx is done before sending.
+func (c *TimeoutChan) deliver(ctx context.Context, item prioritierWrapper) bool {
+	if c.outBatch != nil {
+		return c.deliverBatch(ctx, []prioritierWrapper{item})
+	}
+	start := time.Now()
+	if c.inflight == nil {
+		next := c.advance(item)
+		select {
+		case c.out <- item.Deadliner: // unwrap
+			c.observe(start, item)
+			c.recur(item, next)
+			c.ackJournal(item.id)
+			return true
+		case <-ctx.Done():
+			return false // item is not acknowledged and would be recovered by durable TimeoutChan
+		}
+	}
+	f, dead := c.track(item)
+	switch {
+	case f == nil:
+		return true // stale
+	case dead:
+		if c.deadLetter == nil {
+			return true
+		}
+		select {
+		case c.deadLetter <- item.Deadliner:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	var next nextDeadline
+	if item.flight == nil {
+		next = c.advance(item)
+	}
+	select {
+	case c.out <- item.Deadliner:
+		c.observe(start, item)
+		c.schedule(item.Deadliner, f)
+		c.recur(item, next)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// observe records lateness and blocking time of sending items, which started at start.
+func (c *TimeoutChan) observe(start time.Time, items ...prioritierWrapper) {
+	var (
+		now     = time.Now()
+		instant = c.instant()
+	)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range items {
+		c.lateness.Observe(time.Duration(instant - item.at))
+	}
+	c.blocking.Observe(now.Sub(start))
 }
 
 func (c *TimeoutChan) pushProcess(ctx context.Context) {
+	blocking := c.limit > 0 && c.overflow == OverflowBlock
 	for {
+		// Suspending phase, while queue is full
+		for blocking && c.Len() >= c.limit {
+			if !c.waitResumePush(ctx) {
+				return
+			}
+		}
 		// Working phase
 		select {
 		case in, ok := <-c.in:
@@ -209,51 +803,62 @@ func (c *TimeoutChan) pushProcess(ctx context.Context) {
 				// End push process because `in` channel is closed and drained
 				return
 			}
-			c.push(in)
-			if c.limit == 0 || c.len() < c.limit {
+			item, _ := c.wrap(in) // journaling error is ignored as item is still scheduled
+			if !blocking {
+				c.admit(item, c.overflow, true)
 				continue
-			} // else queue is full, suspense
-		case <-ctx.Done():
-			return
-		}
-		// Suspending phase
-		select {
-		case <-c.resumePush:
+			}
+			// Queue may be filled by TimeoutChan.TryPush in the meantime
+			for c.admit(item, overflowTry, true) == ErrFull {
+				if !c.waitResumePush(ctx) {
+					// Left to TimeoutChan.Clear, or recovered by durable TimeoutChan on shutdown
+					c.mu.Lock()
+					c.held = item
+					c.mu.Unlock()
+					return
+				}
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+func (c *TimeoutChan) waitResumePush(ctx context.Context) bool {
+	select {
+	case <-c.resumePush:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func (c *TimeoutChan) popProcess(ctx context.Context) {
 	for {
-		// Suspending phase
-		select {
-		case <-c.resumePop:
-		case <-c.closePush:
-			if c.len() == 0 {
-				return
+		// Working phase, while queue is not empty
+		for c.liveLen() > 0 {
+			// Sending early sub-phase
+			if item, ok := c.popEarly(); ok {
+				if !c.deliver(ctx, item) {
+					return
+				}
+				continue
 			}
-		case <-ctx.Done():
-			return
-		}
-		// Working phase
-	outerLoop:
-		for {
 			// Peeking sub-phase
+			c.checkClock()
 			reschedule, delta := c.peek()
 			if delta <= 0 {
-				select {
-				case c.out <- c.pop():
-					if c.len() == 0 {
-						break outerLoop // queue is empty, suspend
+				if c.batch > 0 {
+					if !c.deliverBatch(ctx, c.popBatch()) {
+						return
 					}
-				case <-ctx.Done():
+				} else if !c.deliver(ctx, c.pop()) {
 					return
 				}
+				continue
 			}
 			// Spinning sub-phase
-			if d := delta / 2; d > c.resolution {
+			if d := delta / 2; d > c.resolution && c.clockPolicy != ClockWall {
 				delta = d
 			} else if delta > c.resolution {
 				delta = c.resolution
@@ -265,9 +870,31 @@ func (c *TimeoutChan) popProcess(ctx context.Context) {
 					<-timer.C
 				}
 			case <-timer.C:
+				c.mu.Lock()
+				c.wakeups++
+				c.mu.Unlock()
 			case <-ctx.Done():
 				return
 			}
 		}
+		// Suspending phase
+		select {
+		case <-c.resumePop:
+		case <-c.closePush:
+			if c.liveLen() == 0 {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// signal sends a notice to ch without blocking, ch should have a buffer of size 1 so that a pending
+// notice is never lost.
+func signal(ch chan interface{}) {
+	select {
+	case ch <- nil:
+	default:
 	}
 }
@@ -0,0 +1,71 @@
+package goproc
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// WithBatch enables batch mode: Deadliners are sent to TimeoutChan.OutBatch in batches instead of
+// TimeoutChan.Out. When the earliest deadline is reached, all buffered Deadliners with deadlines in
+// the current resolution window - which may be up to resolution later than now - are sent as a
+// single slice in deadline order, with at most max Deadliners in a batch.
+//
+// Batch mode can't be used along with WithAck or WithCallback options.
+func WithBatch(max int) TimeoutChanOption {
+	if max <= 0 {
+		panic("goproc: non-positive batch size")
+	}
+	return func(c *TimeoutChan) {
+		c.batch = max
+	}
+}
+
+// popBatch pops a batch of due Deadliners with a single lock acquisition.
+func (c *TimeoutChan) popBatch() []prioritierWrapper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var (
+		horizon = c.instant() + int64(c.resolution)
+		batch   = make([]prioritierWrapper, 0, c.batch)
+	)
+	for c.pq.Len() > 0 && len(batch) < c.batch {
+		if c.pq.Peek().(prioritierWrapper).at > horizon {
+			break
+		}
+		batch = append(batch, heap.Pop(c.pq).(prioritierWrapper))
+	}
+	if c.limit > 0 && c.buffered()+len(batch) >= c.limit {
+		signal(c.resumePush) // queue is not full, resume
+	}
+	for _, item := range batch {
+		c.unkeyLocked(item)
+		c.countPop(item)
+	}
+	c.batches++
+	return batch
+}
+
+// deliverBatch sends batch to c.outBatch, it returns false if ctx is done before sending.
+func (c *TimeoutChan) deliverBatch(ctx context.Context, batch []prioritierWrapper) bool {
+	var (
+		out   = make([]Deadliner, len(batch))
+		nexts = make([]nextDeadline, len(batch))
+	)
+	for i, item := range batch {
+		out[i] = item.Deadliner // unwrap
+		nexts[i] = c.advance(item)
+	}
+	start := time.Now()
+	select {
+	case c.outBatch <- out:
+		c.observe(start, batch...)
+		for i, item := range batch {
+			c.recur(item, nexts[i])
+			c.ackJournal(item.id)
+		}
+		return true
+	case <-ctx.Done():
+		return false // batch is not acknowledged and would be recovered by durable TimeoutChan
+	}
+}
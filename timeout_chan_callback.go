@@ -0,0 +1,93 @@
+package goproc
+
+import (
+	"context"
+	"time"
+)
+
+// Firer is the interface implemented by a Deadliner carrying its own callback, which is invoked
+// on its deadline by TimeoutChan in callback mode.
+type Firer interface {
+	Deadliner
+	Fire()
+}
+
+// WithCallback enables callback mode: expired Deadliners are dispatched to workers managed by an
+// internal Controller instead of being read from TimeoutChan.Out by user. A worker calls Fire on
+// Firer, or handler with any other Deadliner - which is dropped if handler is nil. With 1 worker,
+// callbacks are invoked serially in deadline order, otherwise they are invoked concurrently.
+//
+// A panic from a callback is recovered and passed to rf if it's not nil. With WithAck option,
+// Deadliners are acknowledged once their callbacks return without panicking, and are redelivered
+// otherwise.
+func WithCallback(handler func(d Deadliner), workers int, rf Recover) TimeoutChanOption {
+	if workers <= 0 {
+		panic("goproc: non-positive callback workers")
+	}
+	return func(c *TimeoutChan) {
+		c.handler = handler
+		c.workers = workers
+		c.recoverFunc = rf
+	}
+}
+
+type funcDeadliner struct {
+	deadline time.Time
+	f        func()
+}
+
+func (d *funcDeadliner) Deadline() time.Time { return d.deadline }
+
+func (d *funcDeadliner) Fire() { d.f() }
+
+// AfterFunc pushes a Firer calling f after duration d, which is only meaningful in callback mode.
+// It's not supported by durable TimeoutChan as functions can't be journaled.
+func (c *TimeoutChan) AfterFunc(d time.Duration, f func()) error {
+	return c.Push(&funcDeadliner{deadline: c.clock.Now().Add(d), f: f})
+}
+
+func (c *TimeoutChan) startDispatch() {
+	c.dispatchCtrl = NewController(c.ctx, "TimeoutChan Dispatch")
+	for i := 0; i < c.workers; i++ {
+		c.dispatchCtrl.Go(c.dispatchProcess)
+	}
+}
+
+func (c *TimeoutChan) dispatchProcess(ctx context.Context) {
+	for {
+		select {
+		case d, ok := <-c.out:
+			if !ok {
+				return
+			}
+			if c.dispatch(d) && c.inflight != nil {
+				c.Ack(d)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch invokes callback of d and reports whether it returns without panicking.
+func (c *TimeoutChan) dispatch(d Deadliner) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.mu.Lock()
+			c.panicked++
+			c.mu.Unlock()
+			if c.recoverFunc != nil {
+				c.recoverFunc(r)
+			}
+		}
+	}()
+	if f, isFirer := d.(Firer); isFirer {
+		f.Fire()
+	} else if c.handler != nil {
+		c.handler(d)
+	}
+	c.mu.Lock()
+	c.dispatched++
+	c.mu.Unlock()
+	return true
+}
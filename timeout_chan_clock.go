@@ -0,0 +1,112 @@
+package goproc
+
+import "time"
+
+// Clock provides time readings for TimeoutChan, which can be injected by WithClock to simulate
+// clock jumps in tests.
+type Clock interface {
+	// Now returns the current wall clock time, which may jump.
+	Now() time.Time
+	// Monotonic returns the elapsed time since a fixed point, which never jumps.
+	Monotonic() time.Duration
+}
+
+// SystemClock is the default Clock reading system time.
+var SystemClock Clock = systemClock{}
+
+var systemEpoch = time.Now()
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Monotonic() time.Duration {
+	return time.Since(systemEpoch)
+}
+
+// read takes a wall clock and monotonic reading from the same instant.
+func (systemClock) read() (time.Time, time.Duration) {
+	now := time.Now()
+	return now, now.Sub(systemEpoch)
+}
+
+// readClock takes a wall clock and monotonic reading from clock, from the same instant if
+// supported.
+func readClock(clock Clock) (time.Time, time.Duration) {
+	if r, ok := clock.(interface {
+		read() (time.Time, time.Duration)
+	}); ok {
+		return r.read()
+	}
+	return clock.Now(), clock.Monotonic()
+}
+
+// ClockPolicy defines how TimeoutChan interprets deadlines against clock jumps.
+type ClockPolicy int
+
+const (
+	// ClockMonotonic interprets deadlines relative to the time they are pushed, a Deadliner is
+	// sent after the duration of Deadline minus Now at pushing has elapsed on monotonic clock,
+	// regardless of wall clock jumps in the meantime. This is the default policy.
+	ClockMonotonic ClockPolicy = iota
+	// ClockWall interprets deadlines as wall clock times, a Deadliner is sent when wall clock
+	// reaches its deadline - earlier if wall clock jumps forward, or later if it jumps backward.
+	// TimeoutChan checks wall clock at least once per resolution while waiting with this policy.
+	ClockWall
+)
+
+// WithClock sets the Clock of TimeoutChan, SystemClock is used by default.
+func WithClock(clock Clock) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.clock = clock
+	}
+}
+
+// WithClockPolicy sets the ClockPolicy of TimeoutChan, and an optional callback onJump, which is
+// called with the offset - positive for forward - on detecting a wall clock jump greater than
+// resolution. Jumps are detected by comparing wall clock and monotonic clock elapsed times while
+// TimeoutChan is working, onJump should not block.
+func WithClockPolicy(policy ClockPolicy, onJump func(jump time.Duration)) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.clockPolicy = policy
+		c.onJump = onJump
+	}
+}
+
+// at returns the priority of deadline: a monotonic instant for ClockMonotonic policy, or Unix
+// time in nanoseconds for ClockWall policy.
+func (c *TimeoutChan) at(deadline time.Time) int64 {
+	if c.clockPolicy == ClockWall {
+		return deadline.UnixNano()
+	}
+	now, mono := readClock(c.clock)
+	return int64(mono + deadline.Sub(now))
+}
+
+// instant returns the current time in the same scale as priorities.
+func (c *TimeoutChan) instant() int64 {
+	if c.clockPolicy == ClockWall {
+		return c.clock.Now().UnixNano()
+	}
+	return int64(c.clock.Monotonic())
+}
+
+// checkClock detects wall clock jumps since last check, it's only called by pop process.
+func (c *TimeoutChan) checkClock() {
+	now, mono := readClock(c.clock)
+	wall := now.UnixNano() // without monotonic reading
+	jump := time.Duration(wall-c.lastWall) - (mono - c.lastMono)
+	first := c.lastWall == 0
+	c.lastWall, c.lastMono = wall, mono
+	if first || (jump <= c.resolution && jump >= -c.resolution) {
+		return
+	}
+	c.mu.Lock()
+	c.clockJumps++
+	c.mu.Unlock()
+	if c.onJump != nil {
+		c.onJump(jump)
+	}
+}
@@ -0,0 +1,125 @@
+package goproc
+
+// Keyed is an optional interface of Deadliner, TimeoutChan with WithDedup option buffers at most one
+// Deadliner for each key.
+type Keyed interface {
+	Key() string
+}
+
+// DedupPolicy defines how TimeoutChan resolves a pushed Keyed Deadliner with the same key as a
+// buffered one.
+type DedupPolicy int
+
+const (
+	// DedupReplace replaces the buffered Deadliner with the pushed one.
+	DedupReplace DedupPolicy = iota
+	// DedupKeepEarliest keeps the one with the earlier deadline, the buffered one wins ties.
+	DedupKeepEarliest
+	// DedupKeepLatest keeps the one with the later deadline, the buffered one wins ties.
+	DedupKeepLatest
+)
+
+// WithDedup enables key-based deduplication of Keyed Deadliners with policy, the optional onReplace
+// is called with each replaced Deadliner, which can be the pushed one. Replaced Deadliners are
+// counted by Replaced in TimeoutChanStats, and pushed ones that are discarded aren't counted by
+// Pushed. Only buffered Deadliners are deduplicated, a Deadliner is free to be pushed again with
+// the same key once it's sent.
+func WithDedup(policy DedupPolicy, onReplace func(replaced Deadliner)) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.dedupPolicy = policy
+		c.onReplace = onReplace
+		c.keys = make(map[string]*keySlot)
+	}
+}
+
+// keySlot tracks the heap index of the buffered Deadliner with key.
+type keySlot struct {
+	key   string
+	index int
+}
+
+// SetIndex implements Indexer.
+func (w prioritierWrapper) SetIndex(i int) {
+	if w.slot != nil {
+		w.slot.index = i
+	}
+}
+
+// keyLocked registers item by its key before pushing, if it's Keyed. It should be called with c.mu
+// held.
+func (c *TimeoutChan) keyLocked(item *prioritierWrapper) {
+	if c.keys == nil || item.flight != nil {
+		return
+	}
+	if k, ok := item.Deadliner.(Keyed); ok {
+		item.slot = &keySlot{key: k.Key()}
+		c.keys[item.slot.key] = item.slot
+	}
+}
+
+// unkeyLocked unregisters item after it leaves the queue. It should be called with c.mu held.
+func (c *TimeoutChan) unkeyLocked(item prioritierWrapper) {
+	if item.slot != nil && c.keys[item.slot.key] == item.slot {
+		delete(c.keys, item.slot.key)
+	}
+}
+
+// removeKey removes the buffered Deadliner with key, which is counted by Cleared, and reports
+// whether it's found. It requires WithDedup option, for subsystems cancelling scheduled Keyed
+// Deadliners.
+func (c *TimeoutChan) removeKey(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	slot, ok := c.keys[key]
+	if !ok {
+		return false
+	}
+	old := c.pq.Remove(slot.index).(prioritierWrapper)
+	c.unkeyLocked(old)
+	c.ackJournal(old.id)
+	if c.limit > 0 && c.buffered() == c.limit-1 {
+		signal(c.resumePush) // queue is not full, resume
+	}
+	c.cleared++
+	return true
+}
+
+// dedupLocked resolves item against the buffered Deadliner with the same key by dedup policy, and
+// pushes item if it wins. It returns the replaced Deadliner, which can be item itself, and whether
+// a buffered Deadliner with the same key is found - otherwise item is left to the caller. It should
+// be called with c.mu held.
+func (c *TimeoutChan) dedupLocked(item prioritierWrapper) (replaced prioritierWrapper, found bool) {
+	if c.keys == nil {
+		return
+	}
+	k, ok := item.Deadliner.(Keyed)
+	if !ok {
+		return
+	}
+	slot, found := c.keys[k.Key()]
+	if !found {
+		return
+	}
+	old := c.pq.heap[slot.index].(prioritierWrapper)
+	switch c.dedupPolicy {
+	case DedupReplace:
+	case DedupKeepEarliest:
+		if item.at >= old.at {
+			old = item
+		}
+	case DedupKeepLatest:
+		if item.at <= old.at {
+			old = item
+		}
+	default:
+		panic("goproc: unknown dedup policy")
+	}
+	c.replaced++
+	if old.slot == slot {
+		c.pq.Remove(slot.index)
+		c.unkeyLocked(old)
+		c.pushLocked(item)
+	}
+	c.ackJournal(old.id)
+	return old, true
+}
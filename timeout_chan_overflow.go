@@ -0,0 +1,92 @@
+package goproc
+
+import (
+	"errors"
+)
+
+var (
+	// ErrFull is returned when pushing to a full limited TimeoutChan with OverflowReject policy.
+	ErrFull = errors.New("goproc: timeout chan is full")
+	// ErrClosed is returned when pushing to a closed TimeoutChan.
+	ErrClosed = errors.New("goproc: timeout chan is closed")
+)
+
+// OverflowPolicy defines the behavior of a limited TimeoutChan when a Deadliner is pushed while its
+// buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks pushing until the buffer is not full, which is the default policy.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject rejects the pushed Deadliner, TimeoutChan.Push returns ErrFull.
+	OverflowReject
+	// OverflowDropNewest drops the pushed Deadliner silently.
+	OverflowDropNewest
+	// OverflowDropLatest drops the Deadliner with the latest deadline among the buffered ones and
+	// the pushed one.
+	OverflowDropLatest
+	// OverflowDeliverEarliest sends the Deadliner with the earliest deadline among the buffered
+	// ones and the pushed one to TimeoutChan.Out immediately, ahead of its deadline. Deadliners sent
+	// early are queued in order ahead of due ones, and at most limit of them are queued - the
+	// pushed Deadliner is dropped like OverflowDropNewest if TimeoutChan.Out is not drained in time.
+	OverflowDeliverEarliest
+
+	// overflowTry rejects the pushed Deadliner like OverflowReject, but without counting or
+	// reporting it as dropped.
+	overflowTry OverflowPolicy = -1
+)
+
+// WithOverflow sets the overflow policy of a limited TimeoutChan. With any policy but
+// OverflowBlock, pushing never blocks and TimeoutChan.Push bypasses background push process. The
+// optional onDrop is called with each dropped - including rejected - Deadliner.
+func WithOverflow(policy OverflowPolicy, onDrop func(d Deadliner)) TimeoutChanOption {
+	return func(c *TimeoutChan) {
+		c.overflow = policy
+		c.onDrop = onDrop
+	}
+}
+
+// makeRoom applies overflow policy for pushing item to a full buffer. It returns the Deadliner to
+// be dropped or sent early, which can be item itself, and whether item should still be pushed.
+func (c *TimeoutChan) makeRoom(item prioritierWrapper, policy OverflowPolicy) (victim prioritierWrapper, admit bool, err error) {
+	switch policy {
+	case OverflowReject, overflowTry:
+		return item, false, ErrFull
+	case OverflowDropNewest:
+		return item, false, nil
+	case OverflowDropLatest:
+		i := c.findBuffered(func(a, b prioritierWrapper) bool { return a.at > b.at })
+		if latest := c.pq.heap[i].(prioritierWrapper); latest.at > item.at {
+			c.pq.Remove(i)
+			c.unkeyLocked(latest)
+			return latest, true, nil
+		}
+		return item, false, nil
+	case OverflowDeliverEarliest:
+		i := c.findBuffered(func(a, b prioritierWrapper) bool { return a.at < b.at })
+		if earliest := c.pq.heap[i].(prioritierWrapper); item.at >= earliest.at {
+			c.pq.Remove(i)
+			c.unkeyLocked(earliest)
+			return earliest, true, nil
+		}
+		return item, false, nil
+	default:
+		panic("goproc: unknown overflow policy")
+	}
+}
+
+// findBuffered returns the index of the best buffered Deadliner by better, excluding redelivery
+// entries. The buffer should not be empty.
+func (c *TimeoutChan) findBuffered(better func(a, b prioritierWrapper) bool) int {
+	found := -1
+	for i, item := range c.pq.heap {
+		w := item.(prioritierWrapper)
+		if w.flight != nil {
+			continue
+		}
+		if found < 0 || better(w, c.pq.heap[found].(prioritierWrapper)) {
+			found = i
+		}
+	}
+	return found
+}
@@ -0,0 +1,61 @@
+package goproc
+
+import "time"
+
+// Recurrer is a Deadliner which re-arms itself. Right before a Recurrer is sent to TimeoutChan.Out
+// (or TimeoutChan.OutBatch, or dispatched in callback mode), Next is called, and after sending the
+// Recurrer is pushed again with the returned deadline, unless false is returned or TimeoutChan is
+// closed - in which case durable TimeoutChan still recovers the recurrence after restart.
+//
+// Next should advance the Recurrer so that Deadline returns the new deadline afterwards, which is
+// required by durable TimeoutChan to recover it - receivers see the advanced Recurrer. Next is
+// called by the pop process of TimeoutChan, while receivers may still hold the Recurrer from
+// previous deliveries, so a Recurrer accessed by receivers should be safe for concurrent use.
+// Recurrences bypass the limit and overflow policy, and are counted by Recurred instead of Pushed
+// in TimeoutChanStats.
+// With WithAck option, a recurrence replaces the in-flight record of the previous delivery.
+type Recurrer interface {
+	Deadliner
+	Next() (time.Time, bool)
+}
+
+// nextDeadline is the next deadline of a Recurrer returned by Recurrer.Next.
+type nextDeadline struct {
+	deadline time.Time
+	ok       bool
+}
+
+// advance calls Next of item before it's sent if it's a Recurrer, so that Next never runs
+// concurrently with receivers of the same delivery.
+func (c *TimeoutChan) advance(item prioritierWrapper) nextDeadline {
+	r, ok := item.Deadliner.(Recurrer)
+	if !ok {
+		return nextDeadline{}
+	}
+	deadline, ok := r.Next()
+	return nextDeadline{deadline: deadline, ok: ok}
+}
+
+// recur pushes the next recurrence of item after it's sent, if next is returned by Recurrer.Next.
+// For durable TimeoutChan, the recurrence is appended to journal before item is acknowledged.
+func (c *TimeoutChan) recur(item prioritierWrapper, next nextDeadline) {
+	if !next.ok {
+		return
+	}
+	recurrence, _ := c.wrap(item.Deadliner) // journaling error is ignored as recurrence is still scheduled
+	recurrence = c.wrapAt(recurrence, next.deadline)
+	recurrence.recurrence = true
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return // recurrence is not acknowledged and would be recovered by durable TimeoutChan
+	}
+	replaced, found := c.dedupLocked(recurrence)
+	if !found {
+		c.pushLocked(recurrence)
+	}
+	c.mu.Unlock()
+	if found && c.onReplace != nil {
+		c.onReplace(replaced.Deadliner)
+	}
+}
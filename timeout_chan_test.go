@@ -2,8 +2,13 @@ package goproc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -214,6 +219,737 @@ func TestTimeoutChanReschedule(t *testing.T) {
 	})
 }
 
+func TestTimeoutChanSnapshot(t *testing.T) {
+	Convey("Test TimeoutChan snapshot", t, func(c C) {
+		const testRounds = 100
+		var tc = NewTimeoutChan(context.Background(), 100*time.Millisecond, 0)
+		defer tc.Shutdown()
+
+		_, ok := tc.NextDeadline()
+		So(ok, ShouldBeFalse)
+
+		factory := &TestDeadlinerFactory{
+			BaseDuration:      1 * time.Minute,
+			RandDurationRange: 1 * time.Minute,
+		}
+		var min time.Time
+		for i := 0; i < testRounds; i++ {
+			in := factory.NewRandTestDeadliner()
+			if i == 0 || in.Before(min) {
+				min = in.Time
+			}
+			tc.Push(in)
+		}
+
+		So(tc.Len(), ShouldEqual, testRounds)
+		next, ok := tc.NextDeadline()
+		So(ok, ShouldBeTrue)
+		So(next, ShouldEqual, min)
+		snapshot := tc.Snapshot()
+		So(len(snapshot), ShouldEqual, testRounds)
+		So(snapshot[0].Deadline(), ShouldEqual, min)
+		for i := 1; i < len(snapshot); i++ {
+			So(snapshot[i-1].Deadline(), ShouldHappenOnOrBefore, snapshot[i].Deadline())
+		}
+		So(tc.Len(), ShouldEqual, testRounds)
+	})
+}
+
+func TestDurableTimeoutChan(t *testing.T) {
+	Convey("Test durable TimeoutChan recovery", t, func(c C) {
+		const testRounds = 100
+		dir, err := ioutil.TempDir("", "goproc-durable-tc")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		opts := JournalOptions{
+			Codec: NewJSONCodec(func() interface{} { return &TestDeadliner{} }),
+			Sync:  SyncNever,
+		}
+		journal, err := OpenJournal(dir, opts)
+		So(err, ShouldBeNil)
+
+		tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, 0, WithJournal(journal))
+		now := time.Now()
+		for i := 0; i < testRounds; i++ {
+			// Half of the items will be overdue on recovery
+			in := TestDeadliner{Time: now.Add(time.Duration(i%2)*time.Hour + time.Duration(i)*time.Millisecond)}
+			So(tc.Push(in), ShouldBeNil)
+		}
+		time.Sleep(200 * time.Millisecond)
+		tc.Shutdown() // without any consumer
+		So(journal.Close(), ShouldBeNil)
+
+		journal, err = OpenJournal(dir, opts)
+		So(err, ShouldBeNil)
+		defer journal.Close()
+		So(journal.Len(), ShouldEqual, testRounds)
+		var (
+			outList  []Deadliner
+			readCtrl = NewController(context.Background(), t.Name())
+		)
+		tc = NewTimeoutChan(context.Background(), 100*time.Millisecond, 0, WithJournal(journal))
+		readCtrl.Go(func(ctx context.Context) {
+			for item := range tc.Out {
+				outList = append(outList, item)
+			}
+		})
+		time.Sleep(500 * time.Millisecond)
+		tc.Shutdown()
+		readCtrl.Wait()
+
+		stat := tc.Stats()
+		fmt.Println(stat)
+		So(stat.Recovered, ShouldEqual, testRounds)
+		So(stat.Popped, ShouldEqual, testRounds/2)
+		So(len(outList), ShouldEqual, testRounds/2)
+		for i := 1; i < len(outList); i++ {
+			So(outList[i-1].Deadline(), ShouldHappenOnOrBefore, outList[i].Deadline())
+		}
+		So(journal.Len(), ShouldEqual, testRounds/2)
+	})
+}
+
+func TestTimeoutChanAck(t *testing.T) {
+	Convey("Test TimeoutChan ack and redelivery", t, func(c C) {
+		const (
+			testRounds      = 100
+			testMaxAttempts = 3
+		)
+		var (
+			deadLetter = make(chan Deadliner, testRounds)
+			tc         = NewTimeoutChan(context.Background(), 10*time.Millisecond, 0,
+				WithAck(200*time.Millisecond, testMaxAttempts, deadLetter))
+			readCtrl   = NewController(context.Background(), t.Name())
+			deliveries = make(map[Deadliner]int)
+			acked      = make(map[Deadliner]bool)
+			now        = time.Now()
+		)
+		readCtrl.Go(func(ctx context.Context) {
+			for item := range tc.Out {
+				deliveries[item]++
+				if item.Deadline().Sub(now)/time.Millisecond%2 == 0 { // ack half of the items
+					acked[item] = true
+					c.So(tc.Ack(item), ShouldBeTrue)
+					c.So(tc.Ack(item), ShouldBeFalse)
+				}
+			}
+		})
+
+		for i := 0; i < testRounds; i++ {
+			So(tc.Push(&TestDeadliner{Time: now.Add(time.Duration(i) * time.Millisecond)}), ShouldBeNil)
+		}
+		tc.Close()
+		readCtrl.Wait()
+		close(deadLetter)
+
+		stat := tc.Stats()
+		fmt.Println(stat)
+		So(stat.Pushed, ShouldEqual, testRounds)
+		So(stat.Popped, ShouldEqual, testRounds)
+		So(stat.Acked, ShouldEqual, testRounds/2)
+		So(stat.Redelivered, ShouldEqual, testRounds/2*(testMaxAttempts-1))
+		So(stat.DeadLettered, ShouldEqual, testRounds/2)
+		So(stat.InFlight, ShouldEqual, 0)
+		for item, n := range deliveries {
+			if acked[item] {
+				So(n, ShouldEqual, 1)
+			} else {
+				So(n, ShouldEqual, testMaxAttempts)
+			}
+		}
+		for item := range deadLetter {
+			So(acked[item], ShouldBeFalse)
+			So(deliveries[item], ShouldEqual, testMaxAttempts)
+		}
+	})
+}
+
+func TestTimeoutChanOverflow(t *testing.T) {
+	Convey("With limited timeout chan overflow setup", t, func(c C) {
+		const (
+			testLimit  = 10
+			testRounds = 20
+		)
+		var (
+			now     = time.Now()
+			inList  = make([]Deadliner, testRounds)
+			dropped []Deadliner
+			onDrop  = func(d Deadliner) { dropped = append(dropped, d) }
+		)
+		for i := range inList {
+			// Far enough so that nothing is sent on time
+			inList[i] = &TestDeadliner{Time: now.Add(time.Hour + time.Duration(i)*time.Second)}
+		}
+		pushAll := func(tc *TimeoutChan, list []Deadliner) (errs []error) {
+			for _, in := range list {
+				errs = append(errs, tc.Push(in))
+			}
+			return
+		}
+		checkBuffered := func(tc *TimeoutChan, expected []Deadliner) {
+			snapshot := tc.Snapshot()
+			So(len(snapshot), ShouldEqual, len(expected))
+			for i := range snapshot {
+				So(snapshot[i], ShouldEqual, expected[i])
+			}
+		}
+
+		Convey("Test reject", func() {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit, WithOverflow(OverflowReject, onDrop))
+			defer tc.Shutdown()
+			errs := pushAll(tc, inList)
+			for i, err := range errs {
+				if i < testLimit {
+					So(err, ShouldBeNil)
+				} else {
+					So(err, ShouldEqual, ErrFull)
+				}
+			}
+			So(dropped, ShouldResemble, inList[testLimit:])
+			So(tc.Stats().Dropped, ShouldEqual, testRounds-testLimit)
+			checkBuffered(tc, inList[:testLimit])
+		})
+
+		Convey("Test drop newest", func() {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit, WithOverflow(OverflowDropNewest, onDrop))
+			defer tc.Shutdown()
+			for _, err := range pushAll(tc, inList) {
+				So(err, ShouldBeNil)
+			}
+			So(dropped, ShouldResemble, inList[testLimit:])
+			So(tc.Stats().Dropped, ShouldEqual, testRounds-testLimit)
+			checkBuffered(tc, inList[:testLimit])
+		})
+
+		Convey("Test drop latest", func() {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit, WithOverflow(OverflowDropLatest, onDrop))
+			defer tc.Shutdown()
+			reversed := make([]Deadliner, testRounds)
+			for i := range inList {
+				reversed[testRounds-1-i] = inList[i]
+			}
+			pushAll(tc, reversed) // every newer one is earlier
+			So(len(dropped), ShouldEqual, testRounds-testLimit)
+			So(tc.Stats().Dropped, ShouldEqual, testRounds-testLimit)
+			checkBuffered(tc, inList[:testLimit])
+		})
+
+		Convey("Test deliver earliest", func() {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit, WithOverflow(OverflowDeliverEarliest, onDrop))
+			defer tc.Shutdown()
+			pushAll(tc, inList) // never blocks
+			for _, in := range inList[:testLimit] {
+				So(<-tc.Out, ShouldEqual, in) // in order
+			}
+			checkBuffered(tc, inList[testLimit:])
+
+			for tc.Stats().Lateness.Count < testLimit { // recorded after sending
+				time.Sleep(time.Millisecond)
+			}
+
+			stat := tc.Stats()
+			So(stat.Early, ShouldEqual, testRounds-testLimit)
+			So(stat.Dropped, ShouldEqual, 0)
+			So(dropped, ShouldBeEmpty)
+			So(stat.Lateness.Max, ShouldEqual, 0) // sent ahead of deadlines
+			So(stat.Reschedules, ShouldEqual, 0)  // pushed in order of deadlines
+
+			// Without receiving, at most testLimit Deadliners are queued to be sent early
+			more := make([]Deadliner, testRounds)
+			for i := range more {
+				more[i] = &TestDeadliner{Time: now.Add(2*time.Hour + time.Duration(i)*time.Second)}
+			}
+			pushAll(tc, more)
+			stat = tc.Stats()
+			early := stat.Early - (testRounds - testLimit)
+			So(early, ShouldBeBetweenOrEqual, testLimit, testLimit+1) // one may be being sent
+			So(stat.Dropped, ShouldEqual, testRounds-early)
+			So(dropped, ShouldResemble, more[early:])
+			all := append(append([]Deadliner{}, inList[testLimit:]...), more[:early]...)
+			for _, in := range all[:early] {
+				So(<-tc.Out, ShouldEqual, in)
+			}
+			checkBuffered(tc, all[early:])
+		})
+	})
+}
+
+func TestTimeoutChanClearBlockedPush(t *testing.T) {
+	Convey("Test clearing full limited timeout chan with blocked producers", t, func(c C) {
+		const (
+			testLimit     = 10
+			testProducers = 4
+			testRounds    = 50
+		)
+		tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit)
+		defer tc.Shutdown()
+		var (
+			at       = time.Now().Add(time.Hour) // nothing is sent
+			accepted int32
+			cleared  int
+		)
+		for i := 0; i < testRounds; i++ {
+			// Races with the push process admitting items of the last round
+			for tc.TryPush(&TestDeadliner{Time: at}) {
+				atomic.AddInt32(&accepted, 1)
+			}
+			var wg sync.WaitGroup
+			for j := 0; j < testProducers; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if tc.Push(&TestDeadliner{Time: at}) == nil { // blocks until cleared
+						atomic.AddInt32(&accepted, 1)
+					}
+				}()
+			}
+			time.Sleep(time.Millisecond)
+			cleared += tc.Clear()
+			wg.Wait()
+		}
+		for i := 0; tc.Stats().Pushed < int(atomic.LoadInt32(&accepted)) && i < 100; i++ {
+			time.Sleep(time.Millisecond) // admitted after received by the push process
+		}
+		stat := tc.Stats()
+		So(stat.Pushed, ShouldEqual, atomic.LoadInt32(&accepted)) // none is lost
+		So(stat.Cleared, ShouldEqual, cleared)
+		So(stat.Pushed, ShouldEqual, stat.Cleared+stat.Len)
+	})
+}
+
+func TestTimeoutChanPushAfterClose(t *testing.T) {
+	Convey("With timeout chan push setup", t, func(c C) {
+		const testLimit = 10
+		in := &TestDeadliner{Time: time.Now().Add(time.Hour)}
+
+		Convey("Test push to full limited timeout chan", func() {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, testLimit)
+			for i := 0; i < testLimit; i++ {
+				So(tc.TryPush(&TestDeadliner{Time: in.Time}), ShouldBeTrue)
+			}
+			So(tc.TryPush(in), ShouldBeFalse)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			So(errors.Is(tc.PushContext(ctx, in), context.DeadlineExceeded), ShouldBeTrue)
+			So(tc.Len(), ShouldEqual, testLimit)
+
+			blockCtrl := NewController(context.Background(), t.Name())
+			blockCtrl.Go(func(ctx context.Context) {
+				c.So(tc.Push(in), ShouldEqual, ErrClosed) // blocks until shutdown
+			})
+			time.Sleep(100 * time.Millisecond)
+			tc.Shutdown()
+			blockCtrl.Wait()
+			So(tc.Stats().Pushed, ShouldEqual, testLimit)
+		})
+
+		for _, limit := range []int{0, testLimit} {
+			tc := NewTimeoutChan(context.Background(), 100*time.Millisecond, limit)
+			Convey(fmt.Sprintf("Test push after shutdown with limit %d", limit), func() {
+				tc.Shutdown()
+				tc.Shutdown() // no-op
+				So(tc.Push(in), ShouldEqual, ErrClosed)
+				So(tc.PushContext(context.Background(), in), ShouldEqual, ErrClosed)
+				So(tc.TryPush(in), ShouldBeFalse)
+				So(tc.Stats().Pushed, ShouldEqual, 0)
+			})
+			Convey(fmt.Sprintf("Test push after close with limit %d", limit), func() {
+				tc.Close()
+				tc.Close() // no-op
+				So(tc.Push(in), ShouldEqual, ErrClosed)
+				So(tc.TryPush(in), ShouldBeFalse)
+			})
+		}
+	})
+}
+
+func TestTimeoutChanCallback(t *testing.T) {
+	Convey("With timeout chan callback setup", t, func(c C) {
+		const testRounds = 100
+		var (
+			now       = time.Now()
+			recovered = make(chan interface{}, testRounds)
+			rf        = func(r interface{}) { recovered <- r }
+		)
+
+		Convey("Test serial dispatch", func() {
+			var handled []Deadliner
+			tc := NewTimeoutChan(context.Background(), 10*time.Millisecond, 0,
+				WithCallback(func(d Deadliner) { handled = append(handled, d) }, 1, rf))
+			var fired int
+			for i := 0; i < testRounds; i++ {
+				So(tc.Push(&TestDeadliner{Time: now.Add(time.Duration(rand.Int63n(100)) * time.Millisecond)}), ShouldBeNil)
+				So(tc.AfterFunc(time.Duration(rand.Int63n(100))*time.Millisecond, func() { fired++ }), ShouldBeNil)
+			}
+			So(tc.AfterFunc(0, func() { panic("test panic") }), ShouldBeNil)
+			tc.Close()
+
+			stat := tc.Stats()
+			fmt.Println(stat)
+			So(stat.Dispatched, ShouldEqual, 2*testRounds)
+			So(stat.Panicked, ShouldEqual, 1)
+			So(<-recovered, ShouldEqual, "test panic")
+			So(fired, ShouldEqual, testRounds)
+			So(len(handled), ShouldEqual, testRounds)
+			for i := 1; i < len(handled); i++ {
+				So(handled[i-1].Deadline(), ShouldHappenOnOrBefore, handled[i].Deadline())
+			}
+		})
+
+		Convey("Test concurrent dispatch with ack", func() {
+			var (
+				mu       sync.Mutex
+				attempts = make(map[Deadliner]int)
+			)
+			tc := NewTimeoutChan(context.Background(), 10*time.Millisecond, 0,
+				WithAck(100*time.Millisecond, 0, nil),
+				WithCallback(func(d Deadliner) {
+					mu.Lock()
+					attempts[d]++
+					n := attempts[d]
+					mu.Unlock()
+					if n == 1 {
+						panic("first attempt fails")
+					}
+				}, 4, rf))
+			for i := 0; i < testRounds; i++ {
+				So(tc.Push(&TestDeadliner{Time: now.Add(time.Duration(rand.Int63n(100)) * time.Millisecond)}), ShouldBeNil)
+			}
+			tc.Close()
+
+			stat := tc.Stats()
+			fmt.Println(stat)
+			So(stat.Panicked, ShouldEqual, testRounds)
+			So(stat.Dispatched, ShouldEqual, testRounds)
+			So(stat.Redelivered, ShouldEqual, testRounds)
+			So(stat.Acked, ShouldEqual, testRounds)
+			for _, n := range attempts {
+				So(n, ShouldEqual, 2)
+			}
+		})
+	})
+}
+
+func TestTimeoutChanBatch(t *testing.T) {
+	Convey("Test TimeoutChan batch delivery", t, func(c C) {
+		const (
+			testRounds     = 1000
+			testBatch      = 10
+			testResolution = 50 * time.Millisecond
+		)
+		var (
+			tc       = NewTimeoutChan(context.Background(), testResolution, 0, WithBatch(testBatch))
+			readCtrl = NewController(context.Background(), t.Name())
+			outList  []Deadliner
+			maxEarly time.Duration
+		)
+		readCtrl.Go(func(ctx context.Context) {
+			for batch := range tc.OutBatch {
+				actual := time.Now()
+				c.So(len(batch), ShouldBeBetweenOrEqual, 1, testBatch)
+				for _, item := range batch {
+					if early := item.Deadline().Sub(actual); early > maxEarly {
+						maxEarly = early
+					}
+				}
+				outList = append(outList, batch...)
+			}
+		})
+
+		factory := &TestDeadlinerFactory{
+			BaseDuration:      100 * time.Millisecond,
+			RandDurationRange: 1 * time.Second,
+		}
+		for i := 0; i < testRounds; i++ {
+			So(tc.Push(factory.NewRandTestDeadliner()), ShouldBeNil)
+		}
+		tc.Close()
+		readCtrl.Wait()
+
+		stat := tc.Stats()
+		fmt.Println(stat)
+		So(stat.Popped, ShouldEqual, testRounds)
+		So(stat.Batches, ShouldBeLessThan, testRounds)
+		So(maxEarly, ShouldBeLessThanOrEqualTo, testResolution)
+		So(len(outList), ShouldEqual, testRounds)
+		for i := 1; i < len(outList); i++ {
+			So(outList[i-1].Deadline(), ShouldHappenOnOrBefore, outList[i].Deadline())
+		}
+	})
+}
+
+func TestTimeoutChanMetrics(t *testing.T) {
+	Convey("Test TimeoutChan metrics", t, func(c C) {
+		const (
+			testRounds     = 100
+			testResolution = 10 * time.Millisecond
+			testBlocking   = 20 * time.Millisecond
+		)
+		tc := NewTimeoutChan(context.Background(), testResolution, 0)
+		now := time.Now()
+		for i := testRounds; i > 0; i-- { // each push reschedules
+			So(tc.Push(TestDeadliner{Time: now.Add(100*time.Millisecond + time.Duration(i)*time.Millisecond)}), ShouldBeNil)
+		}
+		stat := tc.Stats()
+		So(stat.Len, ShouldEqual, testRounds)
+		So(stat.HighWater, ShouldEqual, testRounds)
+		So(stat.Reschedules, ShouldEqual, testRounds-1)
+
+		for i := 0; i < testRounds; i++ {
+			<-tc.Out
+			if i == 0 {
+				time.Sleep(testBlocking) // blocks the next sending
+			}
+		}
+		tc.Close()
+
+		stat = tc.Stats()
+		fmt.Println(stat)
+		So(stat.Len, ShouldEqual, 0)
+		So(stat.HighWater, ShouldEqual, testRounds)
+		So(stat.Wakeups, ShouldBeGreaterThan, 0)
+		So(stat.Lateness.Count, ShouldEqual, testRounds)
+		So(stat.Lateness.Min, ShouldBeGreaterThanOrEqualTo, 0)
+		So(stat.Lateness.Max, ShouldBeGreaterThanOrEqualTo, testBlocking/2)
+		So(stat.Lateness.Percentile(50), ShouldBeLessThan, testResolution)
+		So(stat.Blocking.Count, ShouldEqual, testRounds)
+		So(stat.Blocking.Max, ShouldBeGreaterThanOrEqualTo, testBlocking/2)
+	})
+}
+
+// testRecurrer is safe for concurrent use, as Deadline is called by receivers.
+type testRecurrer struct {
+	mu     sync.Mutex
+	at     time.Time
+	period time.Duration
+	left   int
+}
+
+func (r *testRecurrer) Deadline() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.at
+}
+
+func (r *testRecurrer) Next() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.left == 0 {
+		return time.Time{}, false
+	}
+	r.left--
+	r.at = r.at.Add(r.period)
+	return r.at, true
+}
+
+func TestTimeoutChanRecurring(t *testing.T) {
+	Convey("Test TimeoutChan recurring Deadliners", t, func(c C) {
+		const (
+			testRecurrences = 5
+			testResolution  = 5 * time.Millisecond
+		)
+		var (
+			tc  = NewTimeoutChan(context.Background(), testResolution, 0)
+			now = time.Now()
+			r1  = &testRecurrer{at: now.Add(20 * time.Millisecond), period: 20 * time.Millisecond, left: testRecurrences}
+			r2  = &testRecurrer{at: now.Add(30 * time.Millisecond), period: 30 * time.Millisecond, left: testRecurrences}
+		)
+		So(tc.Push(r1), ShouldBeNil)
+		So(tc.Push(r2), ShouldBeNil)
+		So(tc.Push(TestDeadliner{Time: now.Add(50 * time.Millisecond)}), ShouldBeNil)
+
+		var (
+			received  = make(map[Deadliner][]time.Time)
+			deadlines = make(map[Deadliner][]time.Time)
+		)
+		for i := 0; i < 2*(testRecurrences+1)+1; i++ {
+			item := <-tc.Out
+			received[item] = append(received[item], time.Now())
+			deadlines[item] = append(deadlines[item], item.Deadline())
+		}
+		So(received[r1], ShouldHaveLength, testRecurrences+1)
+		So(received[r2], ShouldHaveLength, testRecurrences+1)
+		for _, r := range []*testRecurrer{r1, r2} {
+			for i := 1; i < len(received[r]); i++ {
+				So(received[r][i].Sub(received[r][i-1]), ShouldBeGreaterThanOrEqualTo, r.period-testResolution)
+			}
+			for i, d := range deadlines[r] {
+				// Advanced by Next before sending, except the last one
+				n := i + 1
+				if n > testRecurrences {
+					n = testRecurrences
+				}
+				So(d, ShouldEqual, deadlines[r][0].Add(time.Duration(n-1)*r.period))
+			}
+		}
+		So(tc.Len(), ShouldEqual, 0)
+		tc.Close()
+
+		stat := tc.Stats()
+		fmt.Println(stat)
+		So(stat.Pushed, ShouldEqual, 3)
+		So(stat.Recurred, ShouldEqual, 2*testRecurrences)
+		So(stat.Popped, ShouldEqual, 2*testRecurrences+3)
+	})
+}
+
+type testClock struct {
+	mu     sync.Mutex
+	epoch  time.Time
+	offset time.Duration
+}
+
+func newTestClock() *testClock {
+	return &testClock{epoch: time.Now()}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Round(0).Add(c.offset) // strip monotonic reading
+}
+
+func (c *testClock) Monotonic() time.Duration {
+	return time.Since(c.epoch)
+}
+
+func (c *testClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+func TestTimeoutChanClock(t *testing.T) {
+	Convey("With test clock setup", t, func(c C) {
+		const testResolution = 10 * time.Millisecond
+		var (
+			clock = newTestClock()
+			mu    sync.Mutex
+			jumps []time.Duration
+		)
+		onJump := func(jump time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			jumps = append(jumps, jump)
+		}
+		receive := func(tc *TimeoutChan) time.Duration {
+			start := time.Now()
+			<-tc.Out
+			return time.Since(start)
+		}
+		Convey("Test monotonic policy", func() {
+			for _, jump := range []time.Duration{time.Hour, -time.Hour} {
+				jumps = nil
+				tc := NewTimeoutChan(context.Background(), testResolution, 0,
+					WithClock(clock), WithClockPolicy(ClockMonotonic, onJump))
+				start := time.Now()
+				So(tc.Push(TestDeadliner{Time: clock.Now().Add(100 * time.Millisecond)}), ShouldBeNil)
+				So(tc.Push(TestDeadliner{Time: clock.Now().Add(200 * time.Millisecond)}), ShouldBeNil)
+				time.Sleep(20 * time.Millisecond)
+				clock.Jump(jump)
+				receive(tc)
+				So(time.Since(start), ShouldBeBetween, 100*time.Millisecond-testResolution, 150*time.Millisecond)
+				receive(tc)
+				So(time.Since(start), ShouldBeBetween, 200*time.Millisecond-testResolution, 250*time.Millisecond)
+				tc.Close()
+
+				mu.Lock()
+				So(jumps, ShouldHaveLength, 1)
+				So(jumps[0], ShouldAlmostEqual, jump, testResolution)
+				mu.Unlock()
+				So(tc.Stats().ClockJumps, ShouldEqual, 1)
+			}
+		})
+		Convey("Test wall policy with forward jump", func() {
+			tc := NewTimeoutChan(context.Background(), testResolution, 0,
+				WithClock(clock), WithClockPolicy(ClockWall, onJump))
+			defer tc.Close()
+			So(tc.Push(TestDeadliner{Time: clock.Now().Add(time.Hour)}), ShouldBeNil)
+			time.Sleep(20 * time.Millisecond)
+			clock.Jump(time.Hour)
+			So(receive(tc), ShouldBeLessThan, 5*testResolution)
+			So(jumps, ShouldHaveLength, 1)
+		})
+		Convey("Test wall policy with backward jump", func() {
+			tc := NewTimeoutChan(context.Background(), testResolution, 0,
+				WithClock(clock), WithClockPolicy(ClockWall, onJump))
+			defer tc.Close()
+			So(tc.Push(TestDeadliner{Time: clock.Now().Add(100 * time.Millisecond)}), ShouldBeNil)
+			time.Sleep(20 * time.Millisecond)
+			clock.Jump(-time.Hour)
+			select {
+			case <-tc.Out:
+				So("received", ShouldBeEmpty) // should not be received before jumping back
+			case <-time.After(200 * time.Millisecond):
+			}
+			clock.Jump(time.Hour)
+			So(receive(tc), ShouldBeLessThan, 5*testResolution)
+			mu.Lock()
+			So(jumps, ShouldHaveLength, 2)
+			mu.Unlock()
+		})
+	})
+}
+
+type testKeyedDeadliner struct {
+	TestDeadliner
+	key string
+}
+
+func (d testKeyedDeadliner) Key() string {
+	return d.key
+}
+
+func TestTimeoutChanDedup(t *testing.T) {
+	Convey("Test TimeoutChan deduplication", t, func(c C) {
+		var (
+			now  = time.Now()
+			at   = func(ms int) TestDeadliner { return TestDeadliner{Time: now.Add(time.Duration(ms) * time.Millisecond)} }
+			a050 = testKeyedDeadliner{TestDeadliner: at(50), key: "a"}
+			a100 = testKeyedDeadliner{TestDeadliner: at(100), key: "a"}
+			a150 = testKeyedDeadliner{TestDeadliner: at(150), key: "a"}
+			b080 = testKeyedDeadliner{TestDeadliner: at(80), key: "b"}
+			u060 = at(60)
+		)
+		for _, testCase := range []struct {
+			policy    DedupPolicy
+			pushed    int
+			replaced  []Deadliner
+			delivered []Deadliner
+		}{
+			{DedupReplace, 6, []Deadliner{a100, a050}, []Deadliner{u060, u060, b080, a150}},
+			{DedupKeepEarliest, 5, []Deadliner{a100, a150}, []Deadliner{a050, u060, u060, b080}},
+			{DedupKeepLatest, 5, []Deadliner{a050, a100}, []Deadliner{u060, u060, b080, a150}},
+		} {
+			var (
+				replaced []Deadliner
+				tc       = NewTimeoutChan(context.Background(), 10*time.Millisecond, 0,
+					WithDedup(testCase.policy, func(d Deadliner) { replaced = append(replaced, d) }))
+			)
+			for _, in := range []Deadliner{a100, u060, b080, a050, u060, a150} {
+				So(tc.Push(in), ShouldBeNil)
+			}
+			So(tc.Len(), ShouldEqual, 4)
+			So(replaced, ShouldResemble, testCase.replaced)
+
+			var delivered []Deadliner
+			for range testCase.delivered {
+				delivered = append(delivered, <-tc.Out)
+			}
+			So(delivered, ShouldResemble, testCase.delivered)
+
+			So(tc.Push(b080), ShouldBeNil) // pushed again after being sent
+			So(<-tc.Out, ShouldResemble, b080)
+			tc.Close()
+
+			stat := tc.Stats()
+			So(stat.Pushed, ShouldEqual, testCase.pushed+1)
+			So(stat.Popped, ShouldEqual, 5)
+			So(stat.Replaced, ShouldEqual, 2)
+		}
+	})
+}
+
 func TestTimeoutChanStarving(t *testing.T) {
 	Convey("Test starving", t, func(c C) {
 		const testRounds = 10
@@ -0,0 +1,297 @@
+package goproc
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EvictionReason tells why an entry is removed from TTLCache.
+type EvictionReason int
+
+const (
+	// EvictExpired means the entry is expired.
+	EvictExpired EvictionReason = iota
+	// EvictCapacity means the entry is the least recently used one evicted for capacity.
+	EvictCapacity
+	// EvictDeleted means the entry is deleted by TTLCache.Delete.
+	EvictDeleted
+)
+
+// String implements fmt.Stringer.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "evicted"
+	case EvictDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(r))
+	}
+}
+
+// TTLCacheStats contains cache statistics returned from TTLCache.Stats().
+type TTLCacheStats struct {
+	Len     int
+	Hits    int
+	Misses  int
+	Expired int
+	Evicted int
+	Deleted int
+}
+
+// String implements fmt.Stringer.
+func (s TTLCacheStats) String() string {
+	return fmt.Sprintf("TTLCacheStats: Len=%d Hits=%d Misses=%d Expired=%d Evicted=%d Deleted=%d",
+		s.Len, s.Hits, s.Misses, s.Expired, s.Evicted, s.Deleted)
+}
+
+// TTLCache is a key-value cache with per-entry TTL and optional LRU capacity bound. Expiries are
+// scheduled by a TimeoutChan and handled by a goroutine owned by the cache Controller.
+type TTLCache struct {
+	ctrl     *Controller
+	tc       *TimeoutChan
+	capacity int
+	onEvict  func(key, value interface{}, reason EvictionReason)
+
+	mu      *sync.Mutex
+	entries map[interface{}]*list.Element
+	lru     *list.List // of *ttlEntry, most recently used at front
+	nextID  uint64
+	stats   TTLCacheStats
+}
+
+type ttlEntry struct {
+	id        uint64 // keys the scheduled ttlExpiry
+	key       interface{}
+	value     interface{}
+	expiry    time.Time // zero for no expiry
+	scheduled time.Time // deadline of the latest scheduled ttlExpiry, zero for none
+}
+
+// ttlExpiry is the Deadliner scheduled for expiry of entry, which is keyed by entry so that
+// rescheduling replaces it. It's stale if entry is removed or rescheduled after it's sent.
+type ttlExpiry struct {
+	entry *ttlEntry
+	at    time.Time
+}
+
+func (e ttlExpiry) Deadline() time.Time {
+	return e.at
+}
+
+func (e ttlExpiry) Key() string {
+	return strconv.FormatUint(e.entry.id, 10)
+}
+
+// NewTTLCache creates a new TTLCache, expiries are handled within resolution. With 0 capacity an
+// unbounded cache will be returned. The optional onEvict is called with each removed entry except
+// the ones overwritten by TTLCache.Set, it should not block.
+func NewTTLCache(ctx context.Context, resolution time.Duration, capacity int,
+	onEvict func(key, value interface{}, reason EvictionReason)) *TTLCache {
+	c := &TTLCache{
+		ctrl:     NewController(ctx, "TTLCache"),
+		tc:       NewTimeoutChan(ctx, resolution, 0, WithDedup(DedupReplace, nil)),
+		capacity: capacity,
+		onEvict:  onEvict,
+		mu:       &sync.Mutex{},
+		entries:  make(map[interface{}]*list.Element),
+		lru:      list.New(),
+	}
+	c.ctrl.Go(c.expireProcess)
+	return c
+}
+
+// Set sets value for key with ttl, non-positive ttl means no expiry. The least recently used entry
+// is evicted if the cache is full.
+func (c *TTLCache) Set(key, value interface{}, ttl time.Duration) {
+	var evicted *ttlEntry
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*ttlEntry)
+		entry.value = value
+		c.lru.MoveToFront(elem)
+		c.expire(entry, ttl)
+		c.mu.Unlock()
+		return
+	}
+	if c.capacity > 0 && c.lru.Len() >= c.capacity {
+		evicted = c.removeLocked(c.lru.Back())
+		c.stats.Evicted++
+	}
+	c.nextID++
+	entry := &ttlEntry{id: c.nextID, key: key, value: value}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.expire(entry, ttl)
+	c.mu.Unlock()
+	if evicted != nil {
+		c.evict(evicted, EvictCapacity)
+	}
+}
+
+// Get returns the value of key, or false if it's not found or expired.
+func (c *TTLCache) Get(key interface{}) (interface{}, bool) {
+	var expired *ttlEntry
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*ttlEntry)
+		if entry.expired(time.Now()) {
+			// Expired but not handled yet
+			expired = c.removeLocked(elem)
+			c.stats.Expired++
+		} else {
+			c.lru.MoveToFront(elem)
+			c.stats.Hits++
+			value := entry.value
+			c.mu.Unlock()
+			return value, true
+		}
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+	if expired != nil {
+		c.evict(expired, EvictExpired)
+	}
+	return nil, false
+}
+
+// Touch extends the TTL of key to ttl from now as sliding expiration, and marks it as recently used.
+// It returns false if key is not found or expired.
+func (c *TTLCache) Touch(key interface{}, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok || elem.Value.(*ttlEntry).expired(time.Now()) {
+		return false
+	}
+	c.lru.MoveToFront(elem)
+	c.expire(elem.Value.(*ttlEntry), ttl)
+	return true
+}
+
+// Delete deletes key and returns whether it's found.
+func (c *TTLCache) Delete(key interface{}) bool {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+	deleted := c.removeLocked(elem)
+	c.stats.Deleted++
+	c.mu.Unlock()
+	c.evict(deleted, EvictDeleted)
+	return true
+}
+
+// Len returns the number of entries in the cache, including expired ones not handled yet.
+func (c *TTLCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns TTLCache statistics.
+func (c *TTLCache) Stats() TTLCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Close stops handling expiries and shuts down the cache goroutines, entries are kept but no longer
+// expired by schedule.
+func (c *TTLCache) Close() {
+	c.ctrl.Shutdown()
+	c.tc.Shutdown()
+}
+
+func (e *ttlEntry) expired(now time.Time) bool {
+	return !e.expiry.IsZero() && !now.Before(e.expiry)
+}
+
+// expire sets the expiry of entry by ttl, and schedules it unless an earlier one is scheduled - in
+// which case entry is rescheduled on expiry handling. It should be called with c.mu held.
+func (c *TTLCache) expire(entry *ttlEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		entry.expiry = time.Time{}
+		c.unschedule(entry)
+		return
+	}
+	entry.expiry = time.Now().Add(ttl)
+	if entry.scheduled.IsZero() || entry.expiry.Before(entry.scheduled) {
+		c.schedule(entry)
+	}
+}
+
+// schedule pushes an expiry of entry at its expiry time, which replaces the scheduled one. It
+// should be called with c.mu held.
+func (c *TTLCache) schedule(entry *ttlEntry) {
+	entry.scheduled = entry.expiry
+	c.tc.Push(ttlExpiry{entry: entry, at: entry.expiry}) // error is ignored as cache is closed
+}
+
+// unschedule removes the scheduled expiry of entry, and marks the one being handled as stale. It
+// should be called with c.mu held.
+func (c *TTLCache) unschedule(entry *ttlEntry) {
+	if entry.scheduled.IsZero() {
+		return
+	}
+	entry.scheduled = time.Time{}
+	c.tc.removeKey(ttlExpiry{entry: entry}.Key())
+}
+
+// removeLocked removes elem from the cache and returns a copy of its entry, the value is released
+// from the removed entry in case a stale expiry still refers to it. It should be called with c.mu
+// held.
+func (c *TTLCache) removeLocked(elem *list.Element) *ttlEntry {
+	entry := c.lru.Remove(elem).(*ttlEntry)
+	delete(c.entries, entry.key)
+	c.unschedule(entry)
+	removed := *entry
+	entry.value = nil
+	return &removed
+}
+
+func (c *TTLCache) evict(entry *ttlEntry, reason EvictionReason) {
+	if c.onEvict != nil {
+		c.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+func (c *TTLCache) expireProcess(ctx context.Context) {
+	for {
+		select {
+		case d := <-c.tc.Out:
+			if entry := c.handle(d.(ttlExpiry)); entry != nil {
+				c.evict(entry, EvictExpired)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handle handles expiry e, and returns the expired entry if any.
+func (c *TTLCache) handle(e ttlExpiry) *ttlEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := e.entry
+	if !entry.scheduled.Equal(e.at) {
+		return nil // stale
+	}
+	entry.scheduled = time.Time{}
+	if !entry.expired(time.Now()) {
+		c.schedule(entry) // extended
+		return nil
+	}
+	c.stats.Expired++
+	return c.removeLocked(c.entries[entry.key])
+}
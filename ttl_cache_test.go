@@ -0,0 +1,121 @@
+package goproc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testEviction struct {
+	key    interface{}
+	value  interface{}
+	reason EvictionReason
+}
+
+func TestTTLCache(t *testing.T) {
+	Convey("With TTL cache setup", t, func(c C) {
+		const (
+			testResolution = 5 * time.Millisecond
+			testTTL        = 100 * time.Millisecond
+		)
+		var (
+			mu        sync.Mutex
+			evictions []testEviction
+			cache     = NewTTLCache(context.Background(), testResolution, 3, func(key, value interface{}, reason EvictionReason) {
+				mu.Lock()
+				defer mu.Unlock()
+				evictions = append(evictions, testEviction{key, value, reason})
+			})
+			evicted = func() []testEviction {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]testEviction(nil), evictions...)
+			}
+		)
+		defer cache.Close()
+
+		Convey("Test get and delete", func() {
+			cache.Set("a", 1, testTTL)
+			cache.Set("b", 2, 0)
+			v, ok := cache.Get("a")
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 1)
+			_, ok = cache.Get("c")
+			So(ok, ShouldBeFalse)
+			cache.Set("a", 3, testTTL)
+			v, _ = cache.Get("a")
+			So(v, ShouldEqual, 3)
+
+			So(cache.Delete("a"), ShouldBeTrue)
+			So(cache.Delete("a"), ShouldBeFalse)
+			_, ok = cache.Get("a")
+			So(ok, ShouldBeFalse)
+			So(evicted(), ShouldResemble, []testEviction{{"a", 3, EvictDeleted}})
+
+			stats := cache.Stats()
+			fmt.Println(stats)
+			So(stats, ShouldResemble, TTLCacheStats{Len: 1, Hits: 2, Misses: 2, Deleted: 1})
+		})
+		Convey("Test expiry", func() {
+			cache.Set("a", 1, testTTL)
+			cache.Set("b", 2, 2*testTTL)
+			cache.Set("c", 3, 0)
+			time.Sleep(testTTL + 2*testResolution)
+			So(evicted(), ShouldResemble, []testEviction{{"a", 1, EvictExpired}})
+			So(cache.Len(), ShouldEqual, 2)
+			time.Sleep(testTTL)
+			So(evicted(), ShouldResemble, []testEviction{{"a", 1, EvictExpired}, {"b", 2, EvictExpired}})
+			_, ok := cache.Get("c")
+			So(ok, ShouldBeTrue)
+			So(cache.Stats().Expired, ShouldEqual, 2)
+		})
+		Convey("Test sliding expiration", func() {
+			cache.Set("a", 1, testTTL)
+			for i := 0; i < 5; i++ {
+				time.Sleep(testTTL / 2)
+				So(cache.Touch("a", testTTL), ShouldBeTrue)
+			}
+			_, ok := cache.Get("a")
+			So(ok, ShouldBeTrue)
+			So(evicted(), ShouldBeEmpty)
+
+			cache.Set("a", 1, 10*testTTL)
+			cache.Set("a", 1, testTTL) // shortened
+			time.Sleep(testTTL + 2*testResolution)
+			So(evicted(), ShouldResemble, []testEviction{{"a", 1, EvictExpired}})
+			So(cache.Touch("a", testTTL), ShouldBeFalse)
+		})
+		Convey("Test LRU eviction", func() {
+			cache.Set("a", 1, 0)
+			cache.Set("b", 2, 0)
+			cache.Set("c", 3, 0)
+			cache.Get("a")
+			cache.Set("d", 4, 0) // evicts b
+			So(cache.Touch("c", 0), ShouldBeTrue)
+			cache.Set("e", 5, 0) // evicts a
+			So(evicted(), ShouldResemble, []testEviction{{"b", 2, EvictCapacity}, {"a", 1, EvictCapacity}})
+			So(cache.Len(), ShouldEqual, 3)
+			So(cache.Stats().Evicted, ShouldEqual, 2)
+		})
+		Convey("Test scheduled expiries after churn", func() {
+			for i := 0; i < 100; i++ {
+				key := i % 5
+				cache.Set(key, i, time.Duration(10-i%10)*testTTL) // evicts, and shortens TTLs
+				if i%7 == 0 {
+					cache.Delete(key)
+				}
+			}
+			So(cache.Len(), ShouldEqual, 3)
+			So(cache.tc.Len(), ShouldEqual, cache.Len()) // no stale expiries
+			for key := 0; key < 5; key++ {
+				cache.Touch(key, 0) // TTL removed
+			}
+			So(cache.tc.Len(), ShouldEqual, 0)
+			So(cache.Len(), ShouldEqual, 3)
+		})
+	})
+}